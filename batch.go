@@ -0,0 +1,262 @@
+package main
+
+import (
+	"backend/lr"
+	"backend/utils"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+)
+
+// Records are decoded, scored and written back in chunks of this size so a
+// large cohort never has to be held in memory all at once.
+const batchChunkSize = 500
+
+type batchResult struct {
+	Index      int                `json:"index"`
+	Prediction *float64           `json:"prediction,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Invalid    []utils.FieldError `json:"errors,omitempty"`
+}
+
+func roundPrediction(prediction float64) float64 {
+	return math.Round(prediction*100) / 100
+}
+
+// batchWriter emits results either as the elements of a single JSON array or
+// as newline-delimited JSON, matching the shape of the request body.
+type batchWriter struct {
+	w       *bufio.Writer
+	flusher http.Flusher
+	ndjson  bool
+	written int
+}
+
+func (bw *batchWriter) write(result batchResult) {
+	line, err := json.Marshal(result)
+	if err != nil {
+		line, _ = json.Marshal(batchResult{Index: result.Index, Error: "Failed to encode result: " + err.Error()})
+	}
+	if !bw.ndjson {
+		if bw.written == 0 {
+			bw.w.WriteString("[\n")
+		} else {
+			bw.w.WriteString(",\n")
+		}
+	}
+	bw.w.Write(line)
+	if bw.ndjson {
+		bw.w.WriteByte('\n')
+	}
+	bw.written++
+}
+
+func (bw *batchWriter) flush() {
+	bw.w.Flush()
+	if bw.flusher != nil {
+		bw.flusher.Flush()
+	}
+}
+
+func (bw *batchWriter) close() {
+	if !bw.ndjson {
+		if bw.written == 0 {
+			bw.w.WriteString("[")
+		}
+		bw.w.WriteString("\n]\n")
+	}
+	bw.flush()
+}
+
+func scoreChunk(model *lr.LinearRegression, start int, records []json.RawMessage, strict bool) []batchResult {
+	results := make([]batchResult, len(records))
+	var features [][]float64
+	var featureIdx []int
+	for i, raw := range records {
+		results[i].Index = start + i
+		x, err := model.FeatureSchema.ParseJSON(raw, strict)
+		var verr *utils.ValidationError
+		if errors.As(err, &verr) {
+			results[i].Error = "Invalid student data"
+			results[i].Invalid = verr.Errors
+			continue
+		}
+		if err != nil {
+			results[i].Error = "Invalid JSON or feature extraction failed: " + err.Error()
+			continue
+		}
+		features = append(features, x)
+		featureIdx = append(featureIdx, i)
+	}
+	for k, prediction := range model.PredictBatch(features) {
+		if math.IsNaN(prediction) || math.IsInf(prediction, 0) {
+			results[featureIdx[k]].Error = "Prediction is not a finite number"
+			continue
+		}
+		rounded := roundPrediction(prediction)
+		results[featureIdx[k]].Prediction = &rounded
+	}
+	return results
+}
+
+func batchPredictHandler(maxRecords int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		enableCORS(w, r)
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		defer r.Body.Close()
+		strict := strictRequested(r)
+
+		body := bufio.NewReader(r.Body)
+		ndjson, err := detectNDJSON(body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		next := ndjsonRecords(body)
+		if !ndjson {
+			decoder := json.NewDecoder(body)
+			if _, err := decoder.Token(); err != nil {
+				http.Error(w, "Invalid JSON array: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			next = arrayRecords(decoder)
+		}
+
+		modelMutex.RLock()
+		model := currentModel
+		modelMutex.RUnlock()
+
+		// Results are flushed while the body is still being read, which
+		// HTTP/1.x servers only allow once full duplex is enabled.
+		http.NewResponseController(w).EnableFullDuplex()
+		out := &batchWriter{w: bufio.NewWriter(w), ndjson: ndjson}
+		out.flusher, _ = w.(http.Flusher)
+		headerSent := false
+		total := 0
+		for {
+			records, readErr := readBatchChunk(next, batchChunkSize)
+			if total+len(records) > maxRecords {
+				limitErr := fmt.Errorf("batch exceeds the limit of %d records", maxRecords)
+				if !headerSent {
+					http.Error(w, limitErr.Error(), http.StatusRequestEntityTooLarge)
+					return
+				}
+				readErr = limitErr
+				records = records[:maxRecords-total]
+			}
+			if !headerSent {
+				if readErr != nil && !errors.Is(readErr, io.EOF) && total+len(records) == 0 {
+					http.Error(w, readErr.Error(), http.StatusBadRequest)
+					return
+				}
+				if ndjson {
+					w.Header().Set("Content-Type", "application/x-ndjson")
+				} else {
+					w.Header().Set("Content-Type", "application/json")
+				}
+				headerSent = true
+			}
+
+			for _, result := range scoreChunk(model, total, records, strict) {
+				out.write(result)
+			}
+			total += len(records)
+
+			if readErr != nil {
+				if !errors.Is(readErr, io.EOF) {
+					// The response is already streaming, so the failure is
+					// reported as one last record instead of an HTTP status.
+					out.write(batchResult{Index: total, Error: readErr.Error()})
+				}
+				break
+			}
+			out.flush()
+		}
+		out.close()
+	}
+}
+
+// detectNDJSON peeks at the first non-whitespace byte: a JSON array starts
+// with '[' and anything else is treated as a stream of JSON objects.
+func detectNDJSON(body *bufio.Reader) (bool, error) {
+	for {
+		b, err := body.Peek(1)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			body.ReadByte()
+		case '[':
+			return false, nil
+		default:
+			return true, nil
+		}
+	}
+}
+
+// arrayRecords returns the elements of a JSON array whose opening bracket
+// has already been consumed. A malformed element ends the array, since the
+// decoder cannot tell where the next one starts.
+func arrayRecords(decoder *json.Decoder) func() (json.RawMessage, error) {
+	return func() (json.RawMessage, error) {
+		if !decoder.More() {
+			if _, err := decoder.Token(); err != nil {
+				return nil, fmt.Errorf("invalid JSON array: %w", err)
+			}
+			return nil, io.EOF
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return raw, nil
+	}
+}
+
+// ndjsonRecords returns one record per non-blank line. Lines are not decoded
+// here: a malformed one fails on its own in scoreChunk and the following
+// lines are still scored.
+func ndjsonRecords(body *bufio.Reader) func() (json.RawMessage, error) {
+	return func() (json.RawMessage, error) {
+		for {
+			line, err := body.ReadBytes('\n')
+			if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+				return json.RawMessage(trimmed), nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// readBatchChunk returns up to size raw records. It returns io.EOF together
+// with the final records once the array or stream is exhausted.
+func readBatchChunk(next func() (json.RawMessage, error), size int) ([]json.RawMessage, error) {
+	records := make([]json.RawMessage, 0, size)
+	for len(records) < size {
+		raw, err := next()
+		if err != nil {
+			return records, err
+		}
+		records = append(records, raw)
+	}
+	return records, nil
+}
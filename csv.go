@@ -0,0 +1,228 @@
+package main
+
+import (
+	"backend/lr"
+	"backend/utils"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	csvPredictionColumn = "PREDICTION"
+	csvErrorColumn      = "ERROR"
+	maxCSVUploadMemory  = 32 << 20
+)
+
+// csvUpload returns the uploaded CSV and a file name for the annotated result,
+// accepting either a multipart form with a "file" field or a raw CSV body.
+func csvUpload(r *http.Request) (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, "predictions.csv", nil
+	}
+	if err := r.ParseMultipartForm(maxCSVUploadMemory); err != nil {
+		return nil, "", fmt.Errorf("invalid multipart upload: %w", err)
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", fmt.Errorf(`multipart upload must contain a "file" field: %w`, err)
+	}
+	base := strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	return file, base + "_predictions.csv", nil
+}
+
+// detectCSVDelimiter picks ';' for spreadsheet exports from locales that use
+// the comma as decimal separator, and ',' otherwise. Numeric values of ';'
+// files are read with a decimal comma, see decimalCommaColumns.
+func detectCSVDelimiter(body *bufio.Reader) rune {
+	peek, _ := body.Peek(4096)
+	if i := bytes.IndexByte(peek, '\n'); i >= 0 {
+		peek = peek[:i]
+	}
+	if bytes.Count(peek, []byte{';'}) > bytes.Count(peek, []byte{','}) {
+		return ';'
+	}
+	return ','
+}
+
+// decimalCommaColumns returns the header positions of the schema's numeric
+// and period columns, whose values are rewritten from "1,5" to "1.5" before
+// parsing.
+func decimalCommaColumns(header []string, schema *utils.FeatureSchema) []int {
+	numeric := make(map[string]bool)
+	for _, c := range schema.Columns {
+		if (c.Type == utils.Numeric || c.Type == utils.Period) && !c.Excluded {
+			numeric[c.Name] = true
+		}
+	}
+	var positions []int
+	for i, column := range header {
+		if numeric[utils.NormalizeCSVHeader(column)] {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// withDecimalPoint returns a copy of record whose values at positions use a
+// decimal point; the record itself is echoed unchanged in the response.
+func withDecimalPoint(record []string, positions []int) []string {
+	values := slices.Clone(record)
+	for _, i := range positions {
+		values[i] = strings.Replace(values[i], ",", ".", 1)
+	}
+	return values
+}
+
+type csvRow struct {
+	record   []string
+	features []float64
+	err      string
+}
+
+// writeCSVChunk appends PREDICTION and ERROR to every row. Predictions use a
+// decimal comma in ';' files, matching how their values were read.
+func writeCSVChunk(out *csv.Writer, model *lr.LinearRegression, rows []csvRow) {
+	var features [][]float64
+	for _, row := range rows {
+		if row.err == "" {
+			features = append(features, row.features)
+		}
+	}
+	predictions := model.PredictBatch(features)
+	k := 0
+	for _, row := range rows {
+		prediction := ""
+		if row.err == "" {
+			prediction = strconv.FormatFloat(roundPrediction(predictions[k]), 'f', 2, 64)
+			if out.Comma == ';' {
+				prediction = strings.Replace(prediction, ".", ",", 1)
+			}
+			k++
+		}
+		out.Write(append(row.record, prediction, row.err))
+	}
+}
+
+func csvPredictHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(w, r)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	defer r.Body.Close()
+	strict := strictRequested(r)
+
+	upload, filename, err := csvUpload(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body := bufio.NewReader(upload)
+	reader := csv.NewReader(body)
+	reader.Comma = detectCSVDelimiter(body)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		http.Error(w, "Failed to read CSV header: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	modelMutex.RLock()
+	model := currentModel
+	modelMutex.RUnlock()
+
+	present := make(map[string]bool, len(header))
+	for _, column := range header {
+		present[utils.NormalizeCSVHeader(column)] = true
+	}
+	var missing []string
+	for _, column := range model.FeatureSchema.ColumnNames() {
+		if !present[column] {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		http.Error(w, "CSV is missing required columns: "+strings.Join(missing, ", "), http.StatusBadRequest)
+		return
+	}
+
+	binder := model.FeatureSchema.Bind(header)
+	var decimalComma []int
+	if reader.Comma == ';' {
+		decimalComma = decimalCommaColumns(header, model.FeatureSchema)
+	}
+
+	http.NewResponseController(w).EnableFullDuplex()
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	out := csv.NewWriter(w)
+	out.Comma = reader.Comma
+	out.Write(append(header, csvPredictionColumn, csvErrorColumn))
+
+	flusher, _ := w.(http.Flusher)
+	rows := make([]csvRow, 0, batchChunkSize)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row := csvRow{record: record}
+		// Keep PREDICTION and ERROR aligned with the header in spreadsheets.
+		if len(record) < len(header) {
+			row.record = append(record, make([]string, len(header)-len(record))...)
+		} else if len(record) > len(header) {
+			row.record = record[:len(header)]
+		}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			row.err = parseErr.Error()
+		case err != nil:
+			// The upload itself could not be read any further.
+			writeCSVChunk(out, model, rows)
+			out.Write(append(make([]string, len(header)+1), err.Error()))
+			out.Flush()
+			return
+		case len(record) != len(header):
+			row.err = fmt.Sprintf("row has %d fields but the header has %d", len(record), len(header))
+		default:
+			values := record
+			if decimalComma != nil {
+				values = withDecimalPoint(record, decimalComma)
+			}
+			row.features, err = binder.FromRecord(values, strict)
+			if err != nil {
+				row.err = err.Error()
+			}
+		}
+		rows = append(rows, row)
+
+		if len(rows) == batchChunkSize {
+			writeCSVChunk(out, model, rows)
+			out.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+			rows = rows[:0]
+		}
+	}
+	writeCSVChunk(out, model, rows)
+	out.Flush()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"backend/lr"
+	"backend/utils"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveTestModel(t *testing.T, schema *utils.FeatureSchema, weights []float64, bias float64) {
+	t.Helper()
+	model := lr.New(len(weights))
+	copy(model.Weights, weights)
+	model.Bias = bias
+	model.FeatureSchema = schema
+	modelMutex.Lock()
+	previous := currentModel
+	currentModel = model
+	modelMutex.Unlock()
+	t.Cleanup(func() {
+		modelMutex.Lock()
+		currentModel = previous
+		modelMutex.Unlock()
+	})
+}
+
+func TestCSVPredictSemicolonRoundTrip(t *testing.T) {
+	serveTestModel(t, &utils.FeatureSchema{
+		Version: utils.SchemaVersion,
+		Columns: []utils.Column{
+			{Name: "EDAD", Type: utils.Numeric},
+			{Name: "CREDITOS", Type: utils.Numeric},
+		},
+	}, []float64{0.5, 0.25}, 1)
+
+	upload := "EDAD;CREDITOS;NOMBRE\n20,5;4;Ana Pérez\n18;2,25;Luis\n"
+	req := httptest.NewRequest(http.MethodPost, "/predict/csv", strings.NewReader(upload))
+	rec := httptest.NewRecorder()
+	csvPredictHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	reader := csv.NewReader(rec.Body)
+	reader.Comma = ';'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("response is not a ';' CSV: %v", err)
+	}
+	want := [][]string{
+		{"EDAD", "CREDITOS", "NOMBRE", csvPredictionColumn, csvErrorColumn},
+		{"20,5", "4", "Ana Pérez", "12,25", ""},
+		{"18", "2,25", "Luis", "10,56", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d rows, want %d: %q", len(records), len(want), records)
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d is %q, want %q", i, records[i], want[i])
+		}
+	}
+}
+
+func TestCSVPredictCommaKeepsDecimalPoint(t *testing.T) {
+	serveTestModel(t, &utils.FeatureSchema{
+		Version: utils.SchemaVersion,
+		Columns: []utils.Column{{Name: "EDAD", Type: utils.Numeric}},
+	}, []float64{0.5}, 1)
+
+	req := httptest.NewRequest(http.MethodPost, "/predict/csv", strings.NewReader("EDAD\n20.5\n"))
+	rec := httptest.NewRecorder()
+	csvPredictHandler(rec, req)
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][1] != "11.25" {
+		t.Errorf("got %q, want a prediction of 11.25", records)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"backend/lr"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultReportGroups are the columns the evaluation report breaks down by
+// default, to spot subgroups the model serves poorly.
+const defaultReportGroups = "PROGRAMA,FACULTAD,GENERO,DISCAPACIDAD"
+
+// groupLabels reads the label of every row for each comma-separated column.
+func (t *trainingSet) groupLabels(columns string) (map[string][]string, error) {
+	groups := make(map[string][]string)
+	for _, name := range strings.Split(columns, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		labels, err := t.column(name)
+		if err != nil {
+			return nil, err
+		}
+		groups[name] = labels
+	}
+	return groups, nil
+}
+
+func printMetricsRow(label string, m lr.RegressionMetrics) {
+	adjusted := "-"
+	if m.AdjustedR2 != nil {
+		adjusted = fmt.Sprintf("%.4f", *m.AdjustedR2)
+	}
+	fmt.Printf("  %-40s %6d %8.4f %8s %8.4f %8.4f %8.4f %8.2f %8.4f\n", label, m.Count, m.R2, adjusted, m.RMSE, m.MAE, m.MedianAE, m.MAPE, m.MaxError)
+}
+
+func printEvaluationReport(report *lr.EvaluationReport) {
+	header := fmt.Sprintf("  %-40s %6s %8s %8s %8s %8s %8s %8s %8s", "", "N", "R²", "Adj R²", "RMSE", "MAE", "MedAE", "MAPE %", "Max err")
+	fmt.Println("Overall:")
+	fmt.Println(header)
+	printMetricsRow("All rows", report.RegressionMetrics)
+
+	fmt.Println("Residual quantiles (target - prediction):")
+	for _, q := range report.ResidualQuantiles {
+		fmt.Printf("  p%-4g %10.4f\n", q.Quantile*100, q.Value)
+	}
+	fmt.Println("Residual histogram:")
+	largest := 0
+	for _, bin := range report.ResidualHistogram {
+		largest = max(largest, bin.Count)
+	}
+	for _, bin := range report.ResidualHistogram {
+		bar := strings.Repeat("#", bin.Count*40/max(largest, 1))
+		fmt.Printf("  [%9.4f, %9.4f) %6d %s\n", bin.Lower, bin.Upper, bin.Count, bar)
+	}
+
+	columns := make([]string, 0, len(report.Groups))
+	for column := range report.Groups {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		fmt.Printf("By %s (worst RMSE first):\n", column)
+		fmt.Println(header)
+		for _, g := range report.Groups[column] {
+			label := g.Group
+			if label == "" {
+				label = "(empty)"
+			}
+			if runes := []rune(label); len(runes) > 40 {
+				label = string(runes[:37]) + "..."
+			}
+			printMetricsRow(label, g.RegressionMetrics)
+		}
+	}
+}
+
+func runEvaluate(args []string) error {
+	fs := flag.NewFlagSet("evaluate", flag.ExitOnError)
+	modelPath := fs.String("model", "model.json", "model file in the JSON or legacy text format")
+	dataPath := fs.String("data", "", "CSV file with one column per schema column plus the target column")
+	target := fs.String("target", "", "name of the CSV column the model predicts")
+	groupBy := fs.String("group-by", defaultReportGroups, "comma-separated CSV columns to break the metrics down by (empty disables)")
+	bins := fs.Int("bins", 10, "number of residual histogram bins")
+	reportPath := fs.String("report", "", "also write the report as JSON to this file")
+	weightColumn := fs.String("weight-column", "", "numeric CSV column with a sample weight for every row")
+	balanceBy := fs.String("balance-by", "", "weight rows so every value of this CSV column carries the same total weight, as in train")
+	fs.Parse(args)
+
+	if *dataPath == "" || *target == "" {
+		fs.Usage()
+		return fmt.Errorf("-data and -target are required")
+	}
+	model, err := loadModel(*modelPath)
+	if err != nil {
+		return fmt.Errorf("failed to load model: %w", err)
+	}
+	set, err := loadTrainingCSV(*dataPath, *target, model.FeatureSchema)
+	if err != nil {
+		return err
+	}
+	groups, err := set.groupLabels(*groupBy)
+	if err != nil {
+		return err
+	}
+	weights, err := set.sampleWeights(*weightColumn, *balanceBy)
+	if err != nil {
+		return err
+	}
+	report, err := model.EvaluationReport(set.xs, set.ys, weights, groups, *bins)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Evaluated %s on %d rows of %s.\n", *modelPath, len(set.xs), *dataPath)
+	printEvaluationReport(report)
+
+	if *reportPath != "" {
+		if err := writeJSONReport(*reportPath, report); err != nil {
+			return err
+		}
+		fmt.Printf("Report written to %s\n", *reportPath)
+	}
+	return nil
+}
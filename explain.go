@@ -0,0 +1,56 @@
+package main
+
+import (
+	"backend/lr"
+	"math"
+	"sort"
+	"strings"
+)
+
+type featureContribution struct {
+	Feature      string   `json:"feature"`
+	Value        *float64 `json:"value,omitempty"`
+	Contribution float64  `json:"contribution"`
+}
+
+type predictionExplanation struct {
+	Bias          float64               `json:"bias"`
+	Contributions []featureContribution `json:"contributions"`
+}
+
+// explainPrediction labels each weight*value term with its feature name and
+// folds every one-hot block into a single "PROGRAMA=AGRONOMIA" style entry.
+func explainPrediction(model *lr.LinearRegression, features []float64) *predictionExplanation {
+	names := model.FeatureNames
+	contributions := model.Contributions(features)
+
+	explanation := &predictionExplanation{Bias: model.Bias}
+	groups := make(map[string]int)
+	for i, contribution := range contributions {
+		column, category, ok := model.FeatureSchema.FeatureGroup(names[i])
+		if !ok {
+			value := features[i]
+			explanation.Contributions = append(explanation.Contributions, featureContribution{
+				Feature:      names[i],
+				Value:        &value,
+				Contribution: contribution,
+			})
+			continue
+		}
+		idx, seen := groups[column]
+		if !seen {
+			idx = len(explanation.Contributions)
+			groups[column] = idx
+			explanation.Contributions = append(explanation.Contributions, featureContribution{Feature: column + "=(none)"})
+		}
+		explanation.Contributions[idx].Contribution += contribution
+		if features[i] != 0 {
+			explanation.Contributions[idx].Feature = column + "=" + strings.TrimSpace(category)
+		}
+	}
+
+	sort.SliceStable(explanation.Contributions, func(a, b int) bool {
+		return math.Abs(explanation.Contributions[a].Contribution) > math.Abs(explanation.Contributions[b].Contribution)
+	})
+	return explanation
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"backend/lr"
+	"backend/utils"
+	"flag"
+	"fmt"
+)
+
+// protectedAttributes labels every row of set with its group for each
+// protected column of the schema and locates the column's feature, if the
+// model uses it.
+func protectedAttributes(set *trainingSet, schema *utils.FeatureSchema) ([]lr.ProtectedAttribute, error) {
+	var attributes []lr.ProtectedAttribute
+	for _, column := range schema.ProtectedColumns() {
+		labels, err := set.column(column.Name)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, lr.ProtectedAttribute{Name: column.Name, Labels: labels, Feature: schema.FeatureIndex(column.Name)})
+	}
+	return attributes, nil
+}
+
+func printFairnessReport(report *lr.FairnessReport) {
+	for _, attr := range report.Attributes {
+		fmt.Printf("%s:\n", attr.Attribute)
+		fmt.Printf("  %-14s %6s %10s %10s %10s %10s %8s %8s %12s\n", "Group", "N", "Mean pred", "Mean y", "Calib gap", "Calib slope", "MAE", "RMSE", "Flip delta")
+		for _, g := range attr.Groups {
+			delta := "-"
+			if g.CounterfactualDelta != nil {
+				delta = fmt.Sprintf("%+.4f", *g.CounterfactualDelta)
+			}
+			fmt.Printf("  %-14s %6d %10.4f %10.4f %+10.4f %10.4f %8.4f %8.4f %12s\n", g.Group, g.Count, g.MeanPrediction, g.MeanTarget, g.CalibrationGap, g.CalibrationSlope, g.MAE, g.RMSE, delta)
+		}
+		fmt.Printf("  Gaps between groups: prediction %.4f, calibration %.4f, MAE %.4f\n", attr.PredictionGap, attr.CalibrationGap, attr.ErrorGap)
+		if cf := attr.Counterfactual; cf != nil {
+			fmt.Printf("  Flipping %s changes predictions by %.4f on average (at most %.4f).\n", cf.Feature, cf.MeanAbsDelta, cf.MaxAbsDelta)
+		} else {
+			fmt.Printf("  The model does not use %s; predictions cannot depend on it directly.\n", attr.Attribute)
+		}
+	}
+}
+
+func runFairness(args []string) error {
+	fs := flag.NewFlagSet("fairness", flag.ExitOnError)
+	modelPath := fs.String("model", "model.json", "model file in the JSON or legacy text format")
+	dataPath := fs.String("data", "", "CSV file with one column per schema column plus the target column")
+	target := fs.String("target", "", "name of the CSV column the model predicts")
+	reportPath := fs.String("report", "", "also write the audit as JSON to this file")
+	fs.Parse(args)
+
+	if *dataPath == "" || *target == "" {
+		fs.Usage()
+		return fmt.Errorf("-data and -target are required")
+	}
+	model, err := loadModel(*modelPath)
+	if err != nil {
+		return fmt.Errorf("failed to load model: %w", err)
+	}
+	set, err := loadTrainingCSV(*dataPath, *target, model.FeatureSchema)
+	if err != nil {
+		return err
+	}
+	attributes, err := protectedAttributes(set, model.FeatureSchema)
+	if err != nil {
+		return err
+	}
+	report, err := model.FairnessAudit(set.xs, set.ys, attributes)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Fairness audit of %s on %d rows of %s.\n", *modelPath, len(set.xs), *dataPath)
+	printFairnessReport(report)
+
+	if *reportPath != "" {
+		if err := writeJSONReport(*reportPath, report); err != nil {
+			return err
+		}
+		fmt.Printf("Report written to %s\n", *reportPath)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package lr
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Split lists the row indices used for training and for evaluation.
+type Split struct {
+	Train []int
+	Test  []int
+}
+
+type Metrics struct {
+	R2   float64 `json:"r2"`
+	MSE  float64 `json:"mse"`
+	RMSE float64 `json:"rmse"`
+	MAE  float64 `json:"mae"`
+}
+
+type FoldResult struct {
+	Fold      int     `json:"fold"`
+	TrainSize int     `json:"train_size"`
+	TestSize  int     `json:"test_size"`
+	Metrics   Metrics `json:"metrics"`
+}
+
+type CrossValidationResult struct {
+	Folds []FoldResult `json:"folds"`
+	Mean  Metrics      `json:"mean"`
+	Std   Metrics      `json:"std"`
+}
+
+// Trainer fits a fresh model on the given rows; CrossValidate calls it once
+// per split so every fold starts from the same configuration.
+type Trainer func(xs [][]float64, ys []float64) (*LinearRegression, error)
+
+// WeightedTrainer is a Trainer that also receives the sample weights of the
+// training rows (nil when the data is unweighted).
+type WeightedTrainer func(xs [][]float64, ys, sampleWeights []float64) (*LinearRegression, error)
+
+// ShuffledIndices returns a permutation of 0..n-1 that only depends on seed.
+func ShuffledIndices(n int, seed int64) []int {
+	return rand.New(rand.NewSource(seed)).Perm(n)
+}
+
+func HoldoutSplit(n int, testFraction float64, seed int64) (Split, error) {
+	if !(testFraction > 0 && testFraction < 1) {
+		return Split{}, fmt.Errorf("test fraction must be between 0 and 1, got %v", testFraction)
+	}
+	testSize := int(math.Round(float64(n) * testFraction))
+	if testSize == 0 || testSize == n {
+		return Split{}, fmt.Errorf("cannot hold out %v of %d rows", testFraction, n)
+	}
+	perm := ShuffledIndices(n, seed)
+	return Split{Train: perm[testSize:], Test: perm[:testSize]}, nil
+}
+
+func KFold(n, k int, seed int64) ([]Split, error) {
+	if k < 2 || k > n {
+		return nil, fmt.Errorf("k must be between 2 and the number of rows (%d), got %d", n, k)
+	}
+	perm := ShuffledIndices(n, seed)
+	folds := make([][]int, k)
+	for i, idx := range perm {
+		folds[i%k] = append(folds[i%k], idx)
+	}
+	return splitsFromFolds(folds), nil
+}
+
+// GroupKFold keeps every row of a group in the same fold, so e.g. grouping by
+// academic period validates each fold on terms the model never saw. Groups
+// are assigned largest first to the fold with the fewest rows; seed only
+// breaks ties between groups of equal size.
+func GroupKFold(groups []string, k int, seed int64) ([]Split, error) {
+	rowsByGroup := make(map[string][]int)
+	for i, g := range groups {
+		rowsByGroup[g] = append(rowsByGroup[g], i)
+	}
+	if k < 2 || k > len(rowsByGroup) {
+		return nil, fmt.Errorf("k must be between 2 and the number of groups (%d), got %d", len(rowsByGroup), k)
+	}
+	names := make([]string, 0, len(rowsByGroup))
+	for g := range rowsByGroup {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(rowsByGroup[names[i]]) > len(rowsByGroup[names[j]])
+	})
+
+	folds := make([][]int, k)
+	for _, g := range names {
+		smallest := 0
+		for f := 1; f < k; f++ {
+			if len(folds[f]) < len(folds[smallest]) {
+				smallest = f
+			}
+		}
+		folds[smallest] = append(folds[smallest], rowsByGroup[g]...)
+	}
+	return splitsFromFolds(folds), nil
+}
+
+// ForwardChainingSplit validates on the future: the distinct periods are
+// sorted and cut into k+1 consecutive blocks, and fold f trains on every row
+// of blocks 0..f-1 and tests on block f. Periods are ordered as strings,
+// which is chronological for labels such as "2023-2" or "20232".
+func ForwardChainingSplit(periods []string, k int) ([]Split, error) {
+	rowsByPeriod := make(map[string][]int)
+	for i, p := range periods {
+		rowsByPeriod[p] = append(rowsByPeriod[p], i)
+	}
+	if k < 1 || k >= len(rowsByPeriod) {
+		return nil, fmt.Errorf("k must be between 1 and the number of periods minus one (%d), got %d", len(rowsByPeriod)-1, k)
+	}
+	names := make([]string, 0, len(rowsByPeriod))
+	for p := range rowsByPeriod {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+
+	blocks := make([][]int, k+1)
+	for i, p := range names {
+		b := i * (k + 1) / len(names)
+		blocks[b] = append(blocks[b], rowsByPeriod[p]...)
+	}
+	splits := make([]Split, k)
+	for f := range splits {
+		for _, block := range blocks[:f+1] {
+			splits[f].Train = append(splits[f].Train, block...)
+		}
+		splits[f].Test = blocks[f+1]
+	}
+	return splits, nil
+}
+
+func splitsFromFolds(folds [][]int) []Split {
+	splits := make([]Split, len(folds))
+	for f := range folds {
+		splits[f].Test = folds[f]
+		for other := range folds {
+			if other != f {
+				splits[f].Train = append(splits[f].Train, folds[other]...)
+			}
+		}
+	}
+	return splits
+}
+
+func subset(xs [][]float64, ys []float64, idx []int) ([][]float64, []float64) {
+	subXs := make([][]float64, len(idx))
+	subYs := make([]float64, len(idx))
+	for i, j := range idx {
+		subXs[i] = xs[j]
+		subYs[i] = ys[j]
+	}
+	return subXs, subYs
+}
+
+// EvaluateMetrics is Evaluate plus the mean absolute error.
+func (lr *LinearRegression) EvaluateMetrics(xs [][]float64, ys []float64) Metrics {
+	return lr.EvaluateMetricsWeighted(xs, ys, nil)
+}
+
+func (lr *LinearRegression) EvaluateMetricsWeighted(xs [][]float64, ys, sampleWeights []float64) Metrics {
+	r2, mse, rmse := lr.EvaluateWeighted(xs, ys, sampleWeights)
+	metrics := Metrics{R2: r2, MSE: mse, RMSE: rmse}
+	if len(xs) != len(ys) || len(xs) == 0 || validateSampleWeights(sampleWeights, len(xs)) != nil {
+		return metrics
+	}
+	var totalWeight float64
+	for i, x := range xs {
+		metrics.MAE += weightAt(sampleWeights, i) * math.Abs(ys[i]-lr.Predict(x))
+		totalWeight += weightAt(sampleWeights, i)
+	}
+	metrics.MAE /= totalWeight
+	return metrics
+}
+
+func CrossValidate(xs [][]float64, ys []float64, splits []Split, train Trainer) (*CrossValidationResult, error) {
+	return CrossValidateWeighted(xs, ys, nil, splits, func(xs [][]float64, ys, _ []float64) (*LinearRegression, error) {
+		return train(xs, ys)
+	})
+}
+
+// CrossValidateWeighted passes each fold's share of sampleWeights to train
+// and weights the test metrics the same way.
+func CrossValidateWeighted(xs [][]float64, ys, sampleWeights []float64, splits []Split, train WeightedTrainer) (*CrossValidationResult, error) {
+	if len(xs) != len(ys) {
+		return nil, fmt.Errorf("xs and ys must have the same length")
+	}
+	if err := validateSampleWeights(sampleWeights, len(xs)); err != nil {
+		return nil, err
+	}
+	if len(splits) == 0 {
+		return nil, fmt.Errorf("no splits to evaluate")
+	}
+	result := &CrossValidationResult{Folds: make([]FoldResult, len(splits))}
+	for f, split := range splits {
+		trainXs, trainYs := subset(xs, ys, split.Train)
+		testXs, testYs := subset(xs, ys, split.Test)
+		model, err := train(trainXs, trainYs, subsetWeights(sampleWeights, split.Train))
+		if err != nil {
+			return nil, fmt.Errorf("fold %d: %w", f+1, err)
+		}
+		result.Folds[f] = FoldResult{
+			Fold:      f + 1,
+			TrainSize: len(split.Train),
+			TestSize:  len(split.Test),
+			Metrics:   model.EvaluateMetricsWeighted(testXs, testYs, subsetWeights(sampleWeights, split.Test)),
+		}
+	}
+	result.Mean, result.Std = aggregateMetrics(result.Folds)
+	return result, nil
+}
+
+func aggregateMetrics(folds []FoldResult) (mean, std Metrics) {
+	n := float64(len(folds))
+	for _, f := range folds {
+		mean.R2 += f.Metrics.R2 / n
+		mean.MSE += f.Metrics.MSE / n
+		mean.RMSE += f.Metrics.RMSE / n
+		mean.MAE += f.Metrics.MAE / n
+	}
+	if len(folds) < 2 {
+		return mean, std
+	}
+	for _, f := range folds {
+		std.R2 += math.Pow(f.Metrics.R2-mean.R2, 2)
+		std.MSE += math.Pow(f.Metrics.MSE-mean.MSE, 2)
+		std.RMSE += math.Pow(f.Metrics.RMSE-mean.RMSE, 2)
+		std.MAE += math.Pow(f.Metrics.MAE-mean.MAE, 2)
+	}
+	std.R2 = math.Sqrt(std.R2 / (n - 1))
+	std.MSE = math.Sqrt(std.MSE / (n - 1))
+	std.RMSE = math.Sqrt(std.RMSE / (n - 1))
+	std.MAE = math.Sqrt(std.MAE / (n - 1))
+	return mean, std
+}
@@ -0,0 +1,125 @@
+package lr
+
+import "sync"
+
+// gradientBlockSize is the number of rows summed sequentially into one
+// partial gradient. Blocks are fixed by row position, not by worker, so the
+// floating-point result is the same for any number of workers.
+const gradientBlockSize = 256
+
+// gradientEngine computes squared-error gradients over standardized rows
+// with a fixed set of goroutines that live for the whole fit. The rows are
+// cut into blocks of gradientBlockSize; each worker accumulates raw
+// (sample-weighted) sums for a contiguous range of blocks into per-block
+// buffers, and compute adds the buffers in block order and divides by the
+// total weight. Every row counts exactly by its weight and the result depends
+// neither on goroutine scheduling nor on the worker count.
+type gradientEngine struct {
+	numFeatures int
+	jobs        []chan gradientJob
+	blocks      []partialGradient
+	wg          sync.WaitGroup
+}
+
+type gradientJob struct {
+	xs            [][]float64
+	ys            []float64
+	sampleWeights []float64
+	weights       []float64
+	bias          float64
+	rows          []int
+	// firstBlock and lastBlock select the half-open range of blocks of rows.
+	firstBlock, lastBlock int
+}
+
+type partialGradient struct {
+	dw               []float64
+	db, loss, weight float64
+}
+
+func newGradientEngine(numFeatures, workers int) *gradientEngine {
+	workers = max(1, workers)
+	e := &gradientEngine{
+		numFeatures: numFeatures,
+		jobs:        make([]chan gradientJob, workers),
+	}
+	for w := range e.jobs {
+		e.jobs[w] = make(chan gradientJob)
+		go e.run(w)
+	}
+	return e
+}
+
+func (e *gradientEngine) run(w int) {
+	for job := range e.jobs[w] {
+		for b := job.firstBlock; b < job.lastBlock; b++ {
+			p := &e.blocks[b]
+			clear(p.dw)
+			p.db, p.loss, p.weight = 0, 0, 0
+			end := min((b+1)*gradientBlockSize, len(job.rows))
+			for _, i := range job.rows[b*gradientBlockSize : end] {
+				x := job.xs[i]
+				err := job.bias - job.ys[i]
+				for j, weight := range job.weights {
+					err += weight * x[j]
+				}
+				sampleWeight := weightAt(job.sampleWeights, i)
+				for j := range p.dw {
+					p.dw[j] += sampleWeight * err * x[j]
+				}
+				p.db += sampleWeight * err
+				p.loss += sampleWeight * err * err
+				p.weight += sampleWeight
+			}
+		}
+		e.wg.Done()
+	}
+}
+
+// compute writes the weighted mean gradient with respect to the weights over
+// the given rows of xs into dw and returns the mean gradient for the bias,
+// the mean squared error and the total sample weight of the rows;
+// sampleWeights may be nil. Workers get contiguous ranges of blocks whose
+// counts differ by at most one.
+func (e *gradientEngine) compute(xs [][]float64, ys, sampleWeights []float64, weights []float64, bias float64, rows []int, dw []float64) (db, loss, total float64) {
+	numBlocks := (len(rows) + gradientBlockSize - 1) / gradientBlockSize
+	for len(e.blocks) < numBlocks {
+		e.blocks = append(e.blocks, partialGradient{dw: make([]float64, e.numFeatures)})
+	}
+	active := min(len(e.jobs), numBlocks)
+	e.wg.Add(active)
+	first := 0
+	for w := 0; w < active; w++ {
+		count := numBlocks / active
+		if w < numBlocks%active {
+			count++
+		}
+		e.jobs[w] <- gradientJob{xs: xs, ys: ys, sampleWeights: sampleWeights, weights: weights, bias: bias, rows: rows, firstBlock: first, lastBlock: first + count}
+		first += count
+	}
+	e.wg.Wait()
+
+	clear(dw)
+	for b := 0; b < numBlocks; b++ {
+		p := &e.blocks[b]
+		for j := range dw {
+			dw[j] += p.dw[j]
+		}
+		db += p.db
+		loss += p.loss
+		total += p.weight
+	}
+	if total == 0 {
+		return 0, 0, 0
+	}
+	for j := range dw {
+		dw[j] /= total
+	}
+	return db / total, loss / total, total
+}
+
+func (e *gradientEngine) close() {
+	for _, jobs := range e.jobs {
+		close(jobs)
+	}
+}
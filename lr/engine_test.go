@@ -0,0 +1,120 @@
+package lr
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func syntheticData(n, p int, seed int64) ([][]float64, []float64) {
+	rng := rand.New(rand.NewSource(seed))
+	xs := make([][]float64, n)
+	ys := make([]float64, n)
+	for i := range xs {
+		xs[i] = make([]float64, p)
+		y := 3.0
+		for j := range xs[i] {
+			xs[i][j] = rng.NormFloat64()*float64(j+1) + float64(j)
+			y += float64(j%3-1) * 0.5 * xs[i][j]
+		}
+		ys[i] = y + rng.NormFloat64()
+	}
+	return xs, ys
+}
+
+func TestFitIsIndependentOfWorkerCount(t *testing.T) {
+	xs, ys := syntheticData(3000, 8, 1)
+	for _, batchSize := range []int{0, 700} {
+		fit := func(workers int) *LinearRegression {
+			opts := DefaultFitOptions()
+			opts.Epochs = 200
+			opts.Workers = workers
+			opts.BatchSize = batchSize
+			seed := int64(7)
+			opts.Seed = &seed
+			model := New(len(xs[0]))
+			if err := model.FitWithOptions(xs, ys, opts); err != nil {
+				t.Fatalf("fit with %d workers: %v", workers, err)
+			}
+			return model
+		}
+		want := fit(1)
+		for _, workers := range []int{2, 3, 8} {
+			got := fit(workers)
+			if got.Bias != want.Bias {
+				t.Errorf("batch size %d, %d workers: bias %v, want %v", batchSize, workers, got.Bias, want.Bias)
+			}
+			for j := range want.Weights {
+				if got.Weights[j] != want.Weights[j] {
+					t.Errorf("batch size %d, %d workers: weight %d is %v, want %v", batchSize, workers, j, got.Weights[j], want.Weights[j])
+				}
+			}
+		}
+	}
+}
+
+const benchRows, benchFeatures = 100000, 60
+
+func benchmarkWorkers() []int {
+	workers := []int{1, 2, 4, runtime.NumCPU()}
+	slices.Sort(workers)
+	return slices.Compact(workers)
+}
+
+// BenchmarkFitGradient times one full-batch gradient over 100k rows.
+func BenchmarkFitGradient(b *testing.B) {
+	xs, ys := syntheticData(benchRows, benchFeatures, 1)
+	weights := make([]float64, benchFeatures)
+	dw := make([]float64, benchFeatures)
+	rows := make([]int, benchRows)
+	for i := range rows {
+		rows[i] = i
+	}
+	for _, workers := range benchmarkWorkers() {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			engine := newGradientEngine(benchFeatures, workers)
+			defer engine.close()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				engine.compute(xs, ys, nil, weights, 0, rows, dw)
+			}
+			b.ReportMetric(float64(benchRows)*float64(b.N)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
+
+// BenchmarkFitEpochs times gradient descent epochs over 100k rows, full-batch
+// and with mini-batches. Normalization happens before the timer starts and
+// the uncertainty statistics of a full fit are not computed.
+func BenchmarkFitEpochs(b *testing.B) {
+	const epochs = 10
+	xs, ys := syntheticData(benchRows, benchFeatures, 1)
+	for _, batchSize := range []int{benchRows, 1024} {
+		for _, workers := range benchmarkWorkers() {
+			b.Run(fmt.Sprintf("batch=%d/workers=%d", batchSize, workers), func(b *testing.B) {
+				opts := DefaultFitOptions()
+				opts.Epochs = epochs
+				opts.Workers = workers
+				opts.FixedEpochs = true
+				model := NewWithSeed(benchFeatures, 1)
+				opts, err := model.prepareFit(opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+				model.computeNormalization(xs, ys, nil)
+				xsNorm, ysNorm := model.normalize(xs, ys)
+				batches := newMemoryBatches(xsNorm, ysNorm, nil, batchSize)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := model.descend(context.Background(), opts, batches); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(benchRows*epochs)*float64(b.N)/b.Elapsed().Seconds(), "rows/s")
+			})
+		}
+	}
+}
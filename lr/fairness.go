@@ -0,0 +1,169 @@
+package lr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ProtectedAttribute describes one attribute to audit. Labels holds the
+// group of every row (e.g. "Femenino"/"Masculino"), and Feature is the index
+// of the 0/1 feature encoding the attribute, or -1 when the model does not
+// use it and there is nothing to flip.
+type ProtectedAttribute struct {
+	Name    string
+	Labels  []string
+	Feature int
+}
+
+// GroupFairness compares one group's predictions with its targets.
+// CalibrationGap is the mean target minus the mean prediction, and
+// CalibrationSlope the slope of the targets regressed on the predictions
+// (1 for a well-calibrated group). CounterfactualDelta is the mean change in
+// prediction when the group's attribute feature is flipped.
+type GroupFairness struct {
+	Group               string   `json:"group"`
+	Count               int      `json:"count"`
+	MeanPrediction      float64  `json:"mean_prediction"`
+	MeanTarget          float64  `json:"mean_target"`
+	CalibrationGap      float64  `json:"calibration_gap"`
+	CalibrationSlope    float64  `json:"calibration_slope"`
+	MAE                 float64  `json:"mae"`
+	RMSE                float64  `json:"rmse"`
+	CounterfactualDelta *float64 `json:"counterfactual_delta,omitempty"`
+}
+
+// CounterfactualResult summarizes how predictions move when the attribute
+// feature of every row is flipped between 0 and 1 and nothing else changes.
+type CounterfactualResult struct {
+	Feature      string  `json:"feature"`
+	MeanAbsDelta float64 `json:"mean_abs_delta"`
+	MaxAbsDelta  float64 `json:"max_abs_delta"`
+}
+
+// AttributeFairness holds the per-group metrics of one attribute and the
+// largest differences between its groups.
+type AttributeFairness struct {
+	Attribute      string                `json:"attribute"`
+	Groups         []GroupFairness       `json:"groups"`
+	PredictionGap  float64               `json:"prediction_gap"`
+	CalibrationGap float64               `json:"calibration_gap"`
+	ErrorGap       float64               `json:"error_gap"`
+	Counterfactual *CounterfactualResult `json:"counterfactual,omitempty"`
+}
+
+type FairnessReport struct {
+	Attributes []AttributeFairness `json:"attributes"`
+}
+
+// FairnessAudit compares predictions on xs and ys across the groups of each
+// attribute: mean prediction, calibration and error per group, the largest
+// gap between groups for each, and a counterfactual test that flips the
+// attribute's 0/1 feature on every row and measures the prediction change.
+func (lr *LinearRegression) FairnessAudit(xs [][]float64, ys []float64, attributes []ProtectedAttribute) (*FairnessReport, error) {
+	if len(xs) != len(ys) {
+		return nil, fmt.Errorf("xs and ys must have the same length")
+	}
+	if len(xs) == 0 {
+		return nil, fmt.Errorf("no rows to audit")
+	}
+	for _, attr := range attributes {
+		if len(attr.Labels) != len(xs) {
+			return nil, fmt.Errorf("attribute %s has %d labels for %d rows", attr.Name, len(attr.Labels), len(xs))
+		}
+		if attr.Feature >= len(lr.Weights) {
+			return nil, fmt.Errorf("attribute %s refers to feature %d but the model has %d", attr.Name, attr.Feature, len(lr.Weights))
+		}
+	}
+
+	predictions := lr.PredictBatch(xs)
+	report := &FairnessReport{}
+	for _, attr := range attributes {
+		var deltas []float64
+		result := AttributeFairness{Attribute: attr.Name}
+		if attr.Feature >= 0 {
+			deltas = lr.counterfactualDeltas(xs, predictions, attr.Feature)
+			cf := &CounterfactualResult{Feature: attr.Name}
+			if attr.Feature < len(lr.FeatureNames) {
+				cf.Feature = lr.FeatureNames[attr.Feature]
+			}
+			for _, d := range deltas {
+				cf.MeanAbsDelta += math.Abs(d) / float64(len(deltas))
+				cf.MaxAbsDelta = math.Max(cf.MaxAbsDelta, math.Abs(d))
+			}
+			result.Counterfactual = cf
+		}
+
+		rowsByGroup := make(map[string][]int)
+		for i, label := range attr.Labels {
+			rowsByGroup[label] = append(rowsByGroup[label], i)
+		}
+		for label, rows := range rowsByGroup {
+			result.Groups = append(result.Groups, groupFairness(label, rows, ys, predictions, deltas))
+		}
+		sort.Slice(result.Groups, func(a, b int) bool { return result.Groups[a].Group < result.Groups[b].Group })
+
+		result.PredictionGap = spread(result.Groups, func(g GroupFairness) float64 { return g.MeanPrediction })
+		result.CalibrationGap = spread(result.Groups, func(g GroupFairness) float64 { return g.CalibrationGap })
+		result.ErrorGap = spread(result.Groups, func(g GroupFairness) float64 { return g.MAE })
+		report.Attributes = append(report.Attributes, result)
+	}
+	return report, nil
+}
+
+// counterfactualDeltas returns, for every row, the prediction with feature
+// flipped between 0 and 1 minus the original prediction.
+func (lr *LinearRegression) counterfactualDeltas(xs [][]float64, predictions []float64, feature int) []float64 {
+	deltas := make([]float64, len(xs))
+	flipped := make([]float64, len(lr.Weights))
+	for i, x := range xs {
+		copy(flipped, x)
+		flipped[feature] = 1 - x[feature]
+		deltas[i] = lr.Predict(flipped) - predictions[i]
+	}
+	return deltas
+}
+
+func groupFairness(label string, rows []int, ys, predictions, deltas []float64) GroupFairness {
+	g := GroupFairness{Group: label, Count: len(rows)}
+	n := float64(len(rows))
+	var meanDelta float64
+	for _, i := range rows {
+		g.MeanPrediction += predictions[i] / n
+		g.MeanTarget += ys[i] / n
+		if deltas != nil {
+			meanDelta += deltas[i] / n
+		}
+	}
+	var sse, covariance, variance float64
+	for _, i := range rows {
+		residual := ys[i] - predictions[i]
+		g.MAE += math.Abs(residual) / n
+		sse += residual * residual
+		dp := predictions[i] - g.MeanPrediction
+		covariance += dp * (ys[i] - g.MeanTarget)
+		variance += dp * dp
+	}
+	g.RMSE = math.Sqrt(sse / n)
+	g.CalibrationGap = g.MeanTarget - g.MeanPrediction
+	if variance > 0 {
+		g.CalibrationSlope = covariance / variance
+	}
+	if deltas != nil {
+		g.CounterfactualDelta = &meanDelta
+	}
+	return g
+}
+
+// spread is the largest minus the smallest value of metric across groups.
+func spread(groups []GroupFairness, metric func(GroupFairness) float64) float64 {
+	if len(groups) == 0 {
+		return 0
+	}
+	lo, hi := metric(groups[0]), metric(groups[0])
+	for _, g := range groups[1:] {
+		lo = math.Min(lo, metric(g))
+		hi = math.Max(hi, metric(g))
+	}
+	return hi - lo
+}
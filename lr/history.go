@@ -0,0 +1,66 @@
+package lr
+
+import "log/slog"
+
+// EpochRecord is one entry of the training history. Loss is the mean
+// standardized squared error over the rows seen during the epoch, measured
+// before each step; ValidationLoss is measured on FitOptions.Validation after
+// the epoch. GradientNorm is the norm of the last step's gradient, after the
+// regularization term and before clipping.
+type EpochRecord struct {
+	Epoch          int      `json:"epoch"`
+	Loss           float64  `json:"loss"`
+	ValidationLoss *float64 `json:"validation_loss,omitempty"`
+	LearningRate   float64  `json:"learning_rate"`
+	GradientNorm   float64  `json:"gradient_norm"`
+}
+
+// EpochStats is what observers receive after every epoch. The deltas are in
+// standardized units, like the weights during training.
+type EpochStats struct {
+	EpochRecord
+	WeightDeltas []float64
+	BiasDelta    float64
+	Final        bool
+	Converged    bool
+}
+
+// ValidationData is scored after every epoch but never used for updates.
+// Weights is optional, as for FitOptions.SampleWeights.
+type ValidationData struct {
+	Xs      [][]float64
+	Ys      []float64
+	Weights []float64
+}
+
+type Observer interface {
+	OnEpoch(stats EpochStats)
+}
+
+type ObserverFunc func(stats EpochStats)
+
+func (f ObserverFunc) OnEpoch(stats EpochStats) { f(stats) }
+
+// SlogObserver logs every n-th epoch and the final one at info level.
+func SlogObserver(logger *slog.Logger, every int) Observer {
+	return ObserverFunc(func(s EpochStats) {
+		if !s.Final && (every <= 0 || s.Epoch%every != 0) {
+			return
+		}
+		attrs := []any{
+			slog.Int("epoch", s.Epoch),
+			slog.Float64("loss", s.Loss),
+			slog.Float64("learning_rate", s.LearningRate),
+			slog.Float64("gradient_norm", s.GradientNorm),
+		}
+		if s.ValidationLoss != nil {
+			attrs = append(attrs, slog.Float64("validation_loss", *s.ValidationLoss))
+		}
+		if s.Final {
+			attrs = append(attrs, slog.Bool("converged", s.Converged))
+			logger.Info("training finished", attrs...)
+			return
+		}
+		logger.Info("training progress", attrs...)
+	})
+}
@@ -0,0 +1,113 @@
+package lr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrRankDeficient is returned by CoefficientInference for gradient descent
+// fits on rank-deficient data. Gradient descent spreads the weight of
+// collinear features among them, while the statistics are computed in the
+// pivoted basis that fixes some of them at zero, so they would not describe
+// the reported weights. FitExact uses that same basis.
+var ErrRankDeficient = errors.New("coefficient inference on a rank-deficient design needs the exact solver")
+
+// CoefficientStats tests whether one weight differs from zero. Estimate is
+// in the original units of the feature. Aliased marks features that are
+// constant or linearly dependent on others in the training data: their
+// weight is not identifiable and the remaining fields are left at zero.
+type CoefficientStats struct {
+	Feature  string  `json:"feature"`
+	Estimate float64 `json:"estimate"`
+	StdError float64 `json:"std_error"`
+	TStat    float64 `json:"t_stat"`
+	PValue   float64 `json:"p_value"`
+	Lower    float64 `json:"lower"`
+	Upper    float64 `json:"upper"`
+	VIF      float64 `json:"vif,omitempty"`
+	Aliased  bool    `json:"aliased,omitempty"`
+}
+
+// CoefficientReport holds the inference for the bias and every weight.
+// Lower and Upper bound each coefficient at the given confidence Level.
+type CoefficientReport struct {
+	Level            float64            `json:"level"`
+	DegreesOfFreedom int                `json:"degrees_of_freedom"`
+	ResidualStdError float64            `json:"residual_std_error"`
+	Intercept        CoefficientStats   `json:"intercept"`
+	Coefficients     []CoefficientStats `json:"coefficients"`
+}
+
+// CoefficientInference computes OLS standard errors, t-statistics,
+// two-sided p-values, confidence intervals and variance inflation factors
+// from the model's uncertainty statistics. They are exact for FitExact and
+// approximate for gradient descent, to the extent it has converged to the
+// least squares solution; they do not account for regularization. Gradient
+// descent fits on rank-deficient data return ErrRankDeficient.
+func (lr *LinearRegression) CoefficientInference(level float64) (*CoefficientReport, error) {
+	if lr.Uncertainty == nil || !lr.hasNormalization() {
+		return nil, ErrNoUncertainty
+	}
+	if !(level > 0 && level < 1) {
+		return nil, fmt.Errorf("confidence level must be between 0 and 1, got %v", level)
+	}
+	u := lr.Uncertainty
+	if rank := u.NumSamples - u.DegreesOfFreedom - 1; rank < len(lr.Weights) && lr.Params.Solver != "qr" {
+		return nil, fmt.Errorf("%w (rank %d of %d)", ErrRankDeficient, rank, len(lr.Weights))
+	}
+	dof := float64(u.DegreesOfFreedom)
+	t := studentTQuantile(1-(1-level)/2, dof)
+	names := lr.FeatureNames
+	if len(names) != len(lr.Weights) {
+		names = defaultFeatureNames(len(lr.Weights))
+	}
+
+	test := func(name string, estimate, variance float64) CoefficientStats {
+		s := CoefficientStats{Feature: name, Estimate: estimate}
+		if variance <= 0 {
+			s.Aliased = true
+			return s
+		}
+		s.StdError = math.Sqrt(variance)
+		s.TStat = estimate / s.StdError
+		s.PValue = 2 * studentTCDF(-math.Abs(s.TStat), dof)
+		s.Lower = estimate - t*s.StdError
+		s.Upper = estimate + t*s.StdError
+		return s
+	}
+
+	report := &CoefficientReport{
+		Level:            level,
+		DegreesOfFreedom: u.DegreesOfFreedom,
+		ResidualStdError: math.Sqrt(u.ResidualVariance),
+	}
+	// The bias is the prediction at x = 0, whose variance is the same
+	// leverage term used for confidence intervals.
+	origin := lr.standardizedRow(make([]float64, len(lr.Weights)))
+	leverage := 1 / float64(u.NumSamples)
+	for j := range origin {
+		for k := range origin {
+			leverage += origin[j] * u.InverseGram[j][k] * origin[k]
+		}
+	}
+	report.Intercept = test("Bias", lr.Bias, u.ResidualVariance*leverage)
+
+	for j, w := range lr.Weights {
+		// Weights are the standardized coefficients divided by the feature's
+		// standard deviation, and so are their standard errors.
+		variance := 0.0
+		if lr.xStds[j] > 1e-8 {
+			variance = u.ResidualVariance * u.InverseGram[j][j] / (lr.xStds[j] * lr.xStds[j])
+		}
+		s := test(names[j], w, variance)
+		if !s.Aliased {
+			// The standardized columns have a sum of squares of n, so
+			// VIF = n * [(CᵀC)⁻¹]jj = 1 / (1 - R²) of the feature regressed
+			// on the others.
+			s.VIF = float64(u.NumSamples) * u.InverseGram[j][j]
+		}
+		report.Coefficients = append(report.Coefficients, s)
+	}
+	return report, nil
+}
@@ -0,0 +1,86 @@
+package lr
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCoefficientInferenceOfExactFit(t *testing.T) {
+	// y = 2/3 + x/2 on x = 1, 2, 3 leaves a residual variance of 1/6 with one
+	// degree of freedom; Sxx = 2 and the mean of x is 2.
+	model := New(1)
+	if _, err := model.FitExact([][]float64{{1}, {2}, {3}}, []float64{1, 2, 2}); err != nil {
+		t.Fatal(err)
+	}
+	report, err := model.CoefficientInference(0.95)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const t975 = 12.706204736
+	interceptT := (2.0 / 3) / math.Sqrt(7.0/18)
+	tests := []struct {
+		name          string
+		got           CoefficientStats
+		estimate, se  float64
+		tStat, pValue float64
+	}{
+		// With one degree of freedom t is Cauchy distributed, so the
+		// two-sided p-value is 1 - 2·atan(|t|)/π: 1/3 for the slope's √3.
+		{"slope", report.Coefficients[0], 0.5, math.Sqrt(1.0 / 12), math.Sqrt(3), 1.0 / 3},
+		{"intercept", report.Intercept, 2.0 / 3, math.Sqrt(7.0 / 18), interceptT, 1 - 2*math.Atan(interceptT)/math.Pi},
+	}
+	for _, tt := range tests {
+		s := tt.got
+		if !closeTo(s.Estimate, tt.estimate, 1e-12) || !closeTo(s.StdError, tt.se, 1e-9) || !closeTo(s.TStat, tt.tStat, 1e-9) {
+			t.Errorf("%s: got estimate %v, std error %v, t %v; want %v, %v, %v", tt.name, s.Estimate, s.StdError, s.TStat, tt.estimate, tt.se, tt.tStat)
+		}
+		if !closeTo(s.PValue, tt.pValue, 1e-9) {
+			t.Errorf("%s: p-value %v, want %v", tt.name, s.PValue, tt.pValue)
+		}
+		if !closeTo(s.Upper-s.Estimate, t975*tt.se, 1e-8) || !closeTo(s.Estimate-s.Lower, t975*tt.se, 1e-8) {
+			t.Errorf("%s: interval [%v, %v], want %v ± %v", tt.name, s.Lower, s.Upper, tt.estimate, t975*tt.se)
+		}
+	}
+	if report.DegreesOfFreedom != 1 || !closeTo(report.ResidualStdError, math.Sqrt(1.0/6), 1e-12) {
+		t.Errorf("got %d degrees of freedom and residual std error %v, want 1 and √(1/6)", report.DegreesOfFreedom, report.ResidualStdError)
+	}
+}
+
+func aliasedData() ([][]float64, []float64) {
+	xs := [][]float64{{1, 2, 0}, {2, 4, 1}, {3, 6, 0}, {4, 8, 1}, {5, 10, 1}, {6, 12, 0}}
+	ys := []float64{4, 8, 10, 14, 17, 18}
+	return xs, ys
+}
+
+func TestCoefficientInferenceMarksAliasedFeatures(t *testing.T) {
+	xs, ys := aliasedData()
+	model := New(3)
+	solver, err := model.FitExact(xs, ys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := model.CoefficientInference(0.95)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped := solver.DependentFeatures[0]
+	for j, s := range report.Coefficients {
+		if s.Aliased != (j == dropped) {
+			t.Errorf("feature %d: aliased %v, want %v", j, s.Aliased, j == dropped)
+		}
+	}
+}
+
+func TestCoefficientInferenceRejectsRankDeficientDescent(t *testing.T) {
+	xs, ys := aliasedData()
+	model := New(3)
+	opts := DefaultFitOptions()
+	opts.Epochs = 50
+	if err := model.FitWithOptions(xs, ys, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := model.CoefficientInference(0.95); !errors.Is(err, ErrRankDeficient) {
+		t.Errorf("got %v, want ErrRankDeficient", err)
+	}
+}
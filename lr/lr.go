@@ -2,41 +2,82 @@ package lr
 
 import (
 	"backend/utils"
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
 type LinearRegression struct {
 	Weights []float64
 	Bias    float64
-	TrainingLoss []float64
-	Converged    bool
+	TrainingLoss  []float64
+	Converged     bool
+	FeatureNames  []string
+	FeatureSchema *utils.FeatureSchema
+	Params        TrainingParams
+	Uncertainty   *UncertaintyStats
+	Seed          int64
+	History       []EpochRecord
+	EarlyStopping *EarlyStoppingResult
 	xMeans []float64
 	xStds  []float64
 	yMean  float64
 	yStd   float64
 }
 
-func New(numFeatures int) *LinearRegression {
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	weights := make([]float64, numFeatures)
-	for i := range weights {
-		weights[i] = (rng.Float64() - 0.5) * 0.02
+// InterruptedError is returned by FitContext when training stopped before
+// running all epochs because its context was cancelled or its time budget ran
+// out. The model is still valid; Cause is context.Canceled or
+// context.DeadlineExceeded.
+type InterruptedError struct {
+	Epochs int
+	Cause  error
+}
+
+func (e *InterruptedError) Error() string {
+	reason := "cancelled"
+	if errors.Is(e.Cause, context.DeadlineExceeded) {
+		reason = "time budget exceeded"
 	}
-	return &LinearRegression{
-		Weights:      weights,
+	return fmt.Sprintf("training interrupted after %d epochs: %s", e.Epochs, reason)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Cause
+}
+
+func New(numFeatures int) *LinearRegression {
+	return NewWithSeed(numFeatures, time.Now().UnixNano())
+}
+
+// NewWithSeed is New with a fixed seed for the initial weights, so that two
+// models built and trained the same way end up with identical coefficients.
+func NewWithSeed(numFeatures int, seed int64) *LinearRegression {
+	lr := &LinearRegression{
 		Bias:         0.0,
 		TrainingLoss: make([]float64, 0),
 		Converged:    false,
 		xMeans:       make([]float64, numFeatures),
 		xStds:        make([]float64, numFeatures),
 	}
+	lr.initWeights(numFeatures, seed)
+	return lr
+}
+
+func (lr *LinearRegression) initWeights(numFeatures int, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	lr.Weights = make([]float64, numFeatures)
+	for i := range lr.Weights {
+		lr.Weights[i] = (rng.Float64() - 0.5) * 0.02
+	}
+	lr.Bias = 0
+	lr.Seed = seed
 }
 
 func (lr *LinearRegression) Predict(x []float64) float64 {
@@ -50,6 +91,19 @@ func (lr *LinearRegression) Predict(x []float64) float64 {
 	return result
 }
 
+// Contributions returns weight*value for every feature; together with Bias
+// they add up to Predict(x).
+func (lr *LinearRegression) Contributions(x []float64) []float64 {
+	if len(x) != len(lr.Weights) {
+		return nil
+	}
+	contributions := make([]float64, len(x))
+	for i, weight := range lr.Weights {
+		contributions[i] = weight * x[i]
+	}
+	return contributions
+}
+
 func (lr *LinearRegression) PredictBatch(xs [][]float64) []float64 {
 	predictions := make([]float64, len(xs))
 	for i, x := range xs {
@@ -59,183 +113,369 @@ func (lr *LinearRegression) PredictBatch(xs [][]float64) []float64 {
 }
 
 func (lr *LinearRegression) Fit(xs [][]float64, ys []float64, epochs int, lrRate float64, workers int) error {
-	if len(xs) != len(ys) {
-		return fmt.Errorf("xs y ys deben tener la misma longitud")
+	return lr.FitRegularized(xs, ys, epochs, lrRate, workers, Regularization{})
+}
+
+func (lr *LinearRegression) FitRegularized(xs [][]float64, ys []float64, epochs int, lrRate float64, workers int, reg Regularization) error {
+	opts := DefaultFitOptions()
+	opts.Epochs = epochs
+	opts.LearningRate = lrRate
+	opts.Workers = workers
+	opts.Regularization = reg
+	return lr.FitWithOptions(xs, ys, opts)
+}
+
+func (lr *LinearRegression) FitWithOptions(xs [][]float64, ys []float64, opts FitOptions) error {
+	return lr.FitContext(context.Background(), xs, ys, opts)
+}
+
+// FitContext is FitWithOptions that stops between epochs once ctx is done or
+// opts.TimeBudget has elapsed. The model is then finished from the weights
+// reached so far (or the best early-stopping snapshot) and remains usable;
+// the returned *InterruptedError says where training stopped.
+func (lr *LinearRegression) FitContext(ctx context.Context, xs [][]float64, ys []float64, opts FitOptions) error {
+	if err := lr.validateTrainingData(xs, ys); err != nil {
+		return err
 	}
-	if len(xs) == 0 {
-		return fmt.Errorf("los datos de entrenamiento no pueden estar vacíos")
+	if err := validateSampleWeights(opts.SampleWeights, len(xs)); err != nil {
+		return err
 	}
-	if len(xs[0]) != len(lr.Weights) {
-		return fmt.Errorf("dimensión de características no coincide")
+	opts, err := lr.prepareFit(opts)
+	if err != nil {
+		return err
 	}
-	n := len(xs)
-	numFeatures := len(lr.Weights)
-	lr.TrainingLoss = make([]float64, 0, epochs)
-	lr.xMeans = make([]float64, numFeatures)
-	lr.xStds = make([]float64, numFeatures)
-	for j := 0; j < numFeatures; j++ {
-		var sum, sumSq float64
-		for i := 0; i < n; i++ {
-			sum += xs[i][j]
-			sumSq += xs[i][j] * xs[i][j]
+	w := normalizedSampleWeights(opts.SampleWeights)
+	es := opts.EarlyStopping
+	if es.Patience > 0 && opts.Validation == nil {
+		fraction := es.ValidationFraction
+		if fraction == 0 {
+			fraction = 0.1
 		}
-		lr.xMeans[j] = sum / float64(n)
-		variance := (sumSq / float64(n)) - (lr.xMeans[j] * lr.xMeans[j])
-		lr.xStds[j] = math.Sqrt(math.Max(variance, 1e-8))
-	}
-	ySum := 0.0
-	for _, y := range ys {
-		ySum += y
-	}
-	lr.yMean = ySum / float64(n)
-	yVar := 0.0
-	for _, y := range ys {
-		yVar += (y - lr.yMean) * (y - lr.yMean)
-	}
-	lr.yStd = math.Sqrt(yVar / float64(n))
-	xsNorm := make([][]float64, n)
-	ysNorm := make([]float64, n)
-	for i := 0; i < n; i++ {
-		xsNorm[i] = make([]float64, numFeatures)
-		for j := 0; j < numFeatures; j++ {
-			if lr.xStds[j] > 1e-8 {
-				xsNorm[i][j] = (xs[i][j] - lr.xMeans[j]) / lr.xStds[j]
-			} else {
-				xsNorm[i][j] = 0
-			}
+		split, err := HoldoutSplit(len(xs), fraction, lr.Seed)
+		if err != nil {
+			return fmt.Errorf("early stopping: %w", err)
 		}
-		if lr.yStd > 1e-8 {
-			ysNorm[i] = (ys[i] - lr.yMean) / lr.yStd
-		} else {
-			ysNorm[i] = 0
+		valXs, valYs := subset(xs, ys, split.Test)
+		opts.Validation = &ValidationData{Xs: valXs, Ys: valYs, Weights: subsetWeights(w, split.Test)}
+		xs, ys = subset(xs, ys, split.Train)
+		w = subsetWeights(w, split.Train)
+	}
+	lr.computeNormalization(xs, ys, w)
+	xsNorm, ysNorm := lr.normalize(xs, ys)
+	batchSize := opts.BatchSize
+	if batchSize == 0 || batchSize > len(xs) {
+		batchSize = len(xs)
+	}
+	err = lr.descend(ctx, opts, newMemoryBatches(xsNorm, ysNorm, w, batchSize))
+	var interrupted *InterruptedError
+	if err != nil && !errors.As(err, &interrupted) {
+		return err
+	}
+	lr.restoreScale()
+	lr.computeUncertainty(xs, ys, w, nil)
+	return err
+}
+
+// prepareFit validates opts, applies the seed and records the parameters of
+// a gradient descent fit.
+func (lr *LinearRegression) prepareFit(opts FitOptions) (FitOptions, error) {
+	if err := opts.Validate(); err != nil {
+		return opts, err
+	}
+	if v := opts.Validation; v != nil {
+		if err := lr.validateTrainingData(v.Xs, v.Ys); err != nil {
+			return opts, fmt.Errorf("validation data: %w", err)
 		}
+		if err := validateSampleWeights(v.Weights, len(v.Xs)); err != nil {
+			return opts, fmt.Errorf("validation data: %w", err)
+		}
+	}
+	if opts.Seed != nil {
+		lr.initWeights(len(lr.Weights), *opts.Seed)
+	}
+	seed := lr.Seed
+	opts.Seed = &seed
+	recorded := opts
+	recorded.Validation, recorded.Observers, recorded.SampleWeights = nil, nil, nil
+	lr.Params = TrainingParams{Solver: "gradient_descent", Weighted: opts.SampleWeights != nil, FitOptions: recorded}
+	return opts, nil
+}
+
+// batchSource feeds descend with standardized rows.
+type batchSource interface {
+	// epoch calls step once per mini-batch of a full pass over the data; w
+	// holds the sample weights of xs, or nil when every row counts once.
+	epoch(rng *rand.Rand, step func(xs [][]float64, ys, w []float64, rows []int)) error
+	// loss is the weighted mean squared error of the current weights over
+	// all rows.
+	loss(lr *LinearRegression) (float64, error)
+}
+
+type memoryBatches struct {
+	xs        [][]float64
+	ys        []float64
+	w         []float64
+	order     []int
+	batchSize int
+}
+
+func newMemoryBatches(xs [][]float64, ys, w []float64, batchSize int) *memoryBatches {
+	order := make([]int, len(xs))
+	for i := range order {
+		order[i] = i
+	}
+	return &memoryBatches{xs: xs, ys: ys, w: w, order: order, batchSize: batchSize}
+}
+
+func (b *memoryBatches) epoch(rng *rand.Rand, step func(xs [][]float64, ys, w []float64, rows []int)) error {
+	n := len(b.order)
+	if b.batchSize < n {
+		rng.Shuffle(n, func(i, j int) { b.order[i], b.order[j] = b.order[j], b.order[i] })
+	}
+	for start := 0; start < n; start += b.batchSize {
+		step(b.xs, b.ys, b.w, b.order[start:min(start+b.batchSize, n)])
 	}
-	currentLR := lrRate
+	return nil
+}
+
+func (b *memoryBatches) loss(lr *LinearRegression) (float64, error) {
+	return lr.calculateLossMultivariate(b.xs, b.ys, b.w), nil
+}
+
+// descend runs the gradient descent epochs on standardized data. The
+// normalization stats must already be set; Weights and Bias stay in
+// standardized units until the caller runs restoreScale.
+func (lr *LinearRegression) descend(ctx context.Context, opts FitOptions, batches batchSource) error {
+	if opts.TimeBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.TimeBudget)
+		defer cancel()
+	}
+	epochs, reg, es := opts.Epochs, opts.Regularization, opts.EarlyStopping
+	numFeatures := len(lr.Weights)
+	optimizer, err := opts.Optimizer.New(numFeatures + 1)
+	if err != nil {
+		return err
+	}
+	engine := newGradientEngine(numFeatures, opts.Workers)
+	defer engine.close()
+	rng := rand.New(rand.NewSource(lr.Seed))
+	var valXs [][]float64
+	var valYs, valW []float64
+	if v := opts.Validation; v != nil {
+		valXs, valYs = lr.normalize(v.Xs, v.Ys)
+		valW = v.Weights
+	}
+	totalDW := make([]float64, numFeatures)
+	params := make([]float64, numFeatures+1)
+	grads := make([]float64, numFeatures+1)
+	prevWeights := make([]float64, numFeatures)
+	plateauFactor := 1.0
+	currentLR := opts.LearningRate
 	bestLoss := math.Inf(1)
 	patienceCounter := 0
-	maxPatience := 15
-	fmt.Printf("Iniciando entrenamiento multivariable con %d características\n", numFeatures)
-	fmt.Printf("Pesos iniciales: ")
-	for i, w := range lr.Weights {
-		fmt.Printf("W%d=%.4f ", i, w)
+	lr.TrainingLoss = nil
+	lr.Converged = false
+	lr.History = nil
+	lr.EarlyStopping = nil
+	var bestWeights []float64
+	var bestBias float64
+	if es.Patience > 0 {
+		lr.EarlyStopping = &EarlyStoppingResult{BestEpoch: -1, BestValidationLoss: math.Inf(1)}
+		bestWeights = make([]float64, numFeatures)
 	}
-	fmt.Printf("Sesgo=%.4f\n", lr.Bias)
-	
+	wait := 0
+
+	var stopErr error
 	for epoch := 0; epoch < epochs; epoch++ {
-		var wg sync.WaitGroup
-		gradW := make([]chan float64, numFeatures)
-		gradB := make(chan float64, workers)
-		for j := 0; j < numFeatures; j++ {
-			gradW[j] = make(chan float64, workers)
+		if err := ctx.Err(); err != nil {
+			stopErr = &InterruptedError{Epochs: epoch, Cause: err}
+			break
 		}
-		batchSize := max(1, n/workers)
-		for w := 0; w < workers; w++ {
-			start := w * batchSize
-			end := utils.Min((w+1)*batchSize, n)
-			if start >= n {
-				break
+		currentLR = opts.LearningRate * opts.Schedule.Factor(epoch, epochs) * plateauFactor
+		copy(prevWeights, lr.Weights)
+		prevBias := lr.Bias
+
+		var epochLoss, gradNorm, seen float64
+		err := batches.epoch(rng, func(xs [][]float64, ys, w []float64, rows []int) {
+			totalDB, batchLoss, batchWeight := engine.compute(xs, ys, w, lr.Weights, lr.Bias, rows, totalDW)
+			epochLoss += batchLoss * batchWeight
+			seen += batchWeight
+			reg.addGradient(totalDW, lr.Weights)
+			gradNorm = totalDB * totalDB
+			for _, g := range totalDW {
+				gradNorm += g * g
 			}
-			wg.Add(1)
-			go func(startIdx, endIdx int) {
-				defer wg.Done()
-				dw := make([]float64, numFeatures)
-				var db float64
-				batchLen := endIdx - startIdx
-				for i := startIdx; i < endIdx; i++ {
-					yPred := lr.Bias
-					for j := 0; j < numFeatures; j++ {
-						yPred += lr.Weights[j] * xsNorm[i][j]
-					}
-					err := yPred - ysNorm[i]
-					for j := 0; j < numFeatures; j++ {
-						dw[j] += err * xsNorm[i][j]
-					}
-					db += err
-				}
+			gradNorm = math.Sqrt(gradNorm)
+			if maxGrad := opts.GradientClip; maxGrad > 0 {
 				for j := 0; j < numFeatures; j++ {
-					gradW[j] <- dw[j] / float64(batchLen)
-				}
-				gradB <- db / float64(batchLen)
-			}(start, end)
-		}
-		wg.Wait()
-		for j := 0; j < numFeatures; j++ {
-			close(gradW[j])
-		}
-		close(gradB)
-		totalDW := make([]float64, numFeatures)
-		var totalDB float64
-		var gradCount float64
-		for j := 0; j < numFeatures; j++ {
-			for v := range gradW[j] {
-				totalDW[j] += v
-				if j == 0 {
-					gradCount++
+					totalDW[j] = math.Max(-maxGrad, math.Min(maxGrad, totalDW[j]))
 				}
+				totalDB = math.Max(-maxGrad, math.Min(maxGrad, totalDB))
 			}
+			copy(params, lr.Weights)
+			params[numFeatures] = lr.Bias
+			copy(grads, totalDW)
+			grads[numFeatures] = totalDB
+			optimizer.Step(params, grads, currentLR)
+			copy(lr.Weights, params[:numFeatures])
+			lr.Bias = params[numFeatures]
+			reg.proximal(lr.Weights, currentLR)
+		})
+		if err != nil {
+			return fmt.Errorf("epoch %d: %w", epoch, err)
 		}
-		for v := range gradB {
-			totalDB += v
-		}
-		if gradCount > 0 {
-			for j := 0; j < numFeatures; j++ {
-				totalDW[j] /= gradCount
-			}
-			totalDB /= gradCount
-		}
-		const maxGrad = 1.0
-		for j := 0; j < numFeatures; j++ {
-			totalDW[j] = math.Max(-maxGrad, math.Min(maxGrad, totalDW[j]))
+		if seen > 0 {
+			epochLoss /= seen
 		}
-		totalDB = math.Max(-maxGrad, math.Min(maxGrad, totalDB))
-		
-		prevWeights := make([]float64, numFeatures)
-		copy(prevWeights, lr.Weights)
-		prevBias := lr.Bias
-		
-		for j := 0; j < numFeatures; j++ {
-			lr.Weights[j] -= currentLR * totalDW[j]
+
+		record := EpochRecord{Epoch: epoch, Loss: epochLoss, LearningRate: currentLR, GradientNorm: gradNorm}
+		if valXs != nil {
+			valLoss := lr.calculateLossMultivariate(valXs, valYs, valW)
+			record.ValidationLoss = &valLoss
 		}
-		lr.Bias -= currentLR * totalDB
-		
-		if epoch%100 == 0 || epoch < 5 || epoch == epochs-1 {
-			loss := lr.calculateLossMultivariate(xsNorm, ysNorm)
-			fmt.Printf("Época %4d: Pérdida=%.6f, LR=%.6f\n", epoch, loss, currentLR)
-			fmt.Printf("    Pesos: ")
-			for j := 0; j < numFeatures; j++ {
-				change := lr.Weights[j] - prevWeights[j]
-				fmt.Printf("W%d=%.4f(Δ%+.4f) ", j, lr.Weights[j], change)
+		lr.History = append(lr.History, record)
+
+		stop := false
+		if r := lr.EarlyStopping; r != nil {
+			if valLoss := *record.ValidationLoss; valLoss < r.BestValidationLoss-es.MinDelta {
+				r.BestEpoch, r.BestValidationLoss = epoch, valLoss
+				copy(bestWeights, lr.Weights)
+				bestBias = lr.Bias
+				wait = 0
+			} else if wait++; wait >= es.Patience {
+				r.Stopped, r.StoppedEpoch = true, epoch
+				stop = true
 			}
-			biasChange := lr.Bias - prevBias
-			fmt.Printf("Sesgo=%.4f(Δ%+.4f)\n", lr.Bias, biasChange)
 		}
-		
 		if epoch%20 == 0 || epoch == epochs-1 {
-			loss := lr.calculateLossMultivariate(xsNorm, ysNorm)
+			loss, err := batches.loss(lr)
+			if err != nil {
+				return fmt.Errorf("epoch %d: %w", epoch, err)
+			}
 			lr.TrainingLoss = append(lr.TrainingLoss, loss)
 			if loss < bestLoss {
 				bestLoss = loss
 				patienceCounter = 0
 			} else {
 				patienceCounter++
-				if patienceCounter >= maxPatience {
-					currentLR *= 0.7
+				if patienceCounter >= opts.Patience {
+					plateauFactor *= opts.DecayFactor
 					patienceCounter = 0
-					fmt.Printf("    → Tasa de aprendizaje reducida a %.6f (paciencia excedida)\n", currentLR)
-					if currentLR < 1e-8 {
+					if currentLR*opts.DecayFactor < opts.MinLearningRate && !opts.FixedEpochs {
 						lr.Converged = true
-						fmt.Printf("    → Convergió: Tasa de aprendizaje demasiado pequeña\n")
-						break
+						stop = true
 					}
 				}
 			}
-			if loss < 1e-6 {
+			if loss < opts.LossThreshold && !opts.FixedEpochs {
 				lr.Converged = true
-				fmt.Printf("    → Convergió: Umbral de pérdida alcanzado\n")
-				break
+				stop = true
+			}
+		}
+
+		if len(opts.Observers) > 0 {
+			stats := EpochStats{
+				EpochRecord:  record,
+				WeightDeltas: make([]float64, numFeatures),
+				BiasDelta:    lr.Bias - prevBias,
+				Final:        stop || epoch == epochs-1,
+				Converged:    lr.Converged,
+			}
+			for j := range stats.WeightDeltas {
+				stats.WeightDeltas[j] = lr.Weights[j] - prevWeights[j]
+			}
+			for _, o := range opts.Observers {
+				o.OnEpoch(stats)
+			}
+		}
+		if stop {
+			break
+		}
+	}
+	if r := lr.EarlyStopping; r != nil && r.BestEpoch >= 0 {
+		copy(lr.Weights, bestWeights)
+		lr.Bias = bestBias
+		loss, err := batches.loss(lr)
+		if err != nil {
+			return err
+		}
+		lr.TrainingLoss = append(lr.TrainingLoss, loss)
+	}
+	return stopErr
+}
+
+func (lr *LinearRegression) validateTrainingData(xs [][]float64, ys []float64) error {
+	if len(xs) != len(ys) {
+		return fmt.Errorf("xs y ys deben tener la misma longitud")
+	}
+	if len(xs) == 0 {
+		return fmt.Errorf("los datos de entrenamiento no pueden estar vacíos")
+	}
+	if len(xs[0]) != len(lr.Weights) {
+		return fmt.Errorf("dimensión de características no coincide")
+	}
+	return nil
+}
+
+// computeNormalization sets the (weighted, when w is not nil) means and
+// population standard deviations used to standardize the data, accumulated
+// with the same Welford update FitStream uses.
+func (lr *LinearRegression) computeNormalization(xs [][]float64, ys, w []float64) {
+	n := len(xs)
+	numFeatures := len(lr.Weights)
+	lr.xMeans = make([]float64, numFeatures)
+	lr.xStds = make([]float64, numFeatures)
+	for j := 0; j < numFeatures; j++ {
+		var acc welford
+		for i := 0; i < n; i++ {
+			acc.add(xs[i][j], weightAt(w, i))
+		}
+		lr.xMeans[j] = acc.mean
+		lr.xStds[j] = math.Sqrt(math.Max(acc.variance(), 1e-8))
+	}
+	var yAcc welford
+	for i, y := range ys {
+		yAcc.add(y, weightAt(w, i))
+	}
+	lr.yMean = yAcc.mean
+	lr.yStd = math.Sqrt(yAcc.variance())
+}
+
+func (lr *LinearRegression) normalize(xs [][]float64, ys []float64) ([][]float64, []float64) {
+	xsNorm := make([][]float64, len(xs))
+	for i := range xsNorm {
+		xsNorm[i] = make([]float64, len(lr.Weights))
+	}
+	ysNorm := make([]float64, len(ys))
+	lr.normalizeInto(xs, ys, xsNorm, ysNorm)
+	return xsNorm, ysNorm
+}
+
+// normalizeInto standardizes xs and ys into preallocated buffers of the same
+// shape.
+func (lr *LinearRegression) normalizeInto(xs [][]float64, ys []float64, xsNorm [][]float64, ysNorm []float64) {
+	numFeatures := len(lr.Weights)
+	for i := range xs {
+		for j := 0; j < numFeatures; j++ {
+			if lr.xStds[j] > 1e-8 {
+				xsNorm[i][j] = (xs[i][j] - lr.xMeans[j]) / lr.xStds[j]
+			} else {
+				xsNorm[i][j] = 0
 			}
 		}
+		if lr.yStd > 1e-8 {
+			ysNorm[i] = (ys[i] - lr.yMean) / lr.yStd
+		} else {
+			ysNorm[i] = 0
+		}
 	}
+}
+
+// restoreScale maps Weights and Bias learned on standardized data back to the
+// original feature and target units.
+func (lr *LinearRegression) restoreScale() {
+	numFeatures := len(lr.Weights)
 	if lr.yStd > 1e-8 {
 		for j := 0; j < numFeatures; j++ {
 			if lr.xStds[j] > 1e-8 {
@@ -248,45 +488,55 @@ func (lr *LinearRegression) Fit(xs [][]float64, ys []float64, epochs int, lrRate
 		}
 		lr.Bias = lr.Bias*lr.yStd + biasTerm
 	}
-	return nil
 }
 
-func (lr *LinearRegression) calculateLossMultivariate(xs [][]float64, ys []float64) float64 {
-	var totalLoss float64
+func (lr *LinearRegression) calculateLossMultivariate(xs [][]float64, ys, w []float64) float64 {
+	var totalLoss, totalWeight float64
 	for i := range xs {
 		pred := lr.Bias
 		for j := range lr.Weights {
 			pred += lr.Weights[j] * xs[i][j]
 		}
 		err := pred - ys[i]
-		totalLoss += err * err
+		totalLoss += weightAt(w, i) * err * err
+		totalWeight += weightAt(w, i)
 	}
-	return totalLoss / float64(len(xs))
+	return totalLoss / totalWeight
 }
 
-func (lr *LinearRegression) GetTrainingMetrics() (loss []float64, converged bool) {
-	return lr.TrainingLoss, lr.Converged
+// GetTrainingMetrics returns the per-epoch history of the last gradient
+// descent fit, including validation loss when validation data was supplied.
+func (lr *LinearRegression) GetTrainingMetrics() (history []EpochRecord, converged bool) {
+	return lr.History, lr.Converged
 }
 
 func (lr *LinearRegression) Evaluate(xs [][]float64, ys []float64) (r2, mse, rmse float64) {
-	if len(xs) != len(ys) || len(xs) == 0 {
+	return lr.EvaluateWeighted(xs, ys, nil)
+}
+
+// EvaluateWeighted is Evaluate with every row's squared error (and its
+// contribution to the target variance) multiplied by its sample weight; nil
+// weights every row equally.
+func (lr *LinearRegression) EvaluateWeighted(xs [][]float64, ys, w []float64) (r2, mse, rmse float64) {
+	if len(xs) != len(ys) || len(xs) == 0 || validateSampleWeights(w, len(xs)) != nil {
 		return 0, 0, 0
 	}
 	predictions := lr.PredictBatch(xs)
-	yMean := 0.0
-	for _, y := range ys {
-		yMean += y
+	yMean, totalWeight := 0.0, 0.0
+	for i, y := range ys {
+		yMean += weightAt(w, i) * y
+		totalWeight += weightAt(w, i)
 	}
-	yMean /= float64(len(ys))
+	yMean /= totalWeight
 	var ssRes, ssTot float64
 	for i := range ys {
-		ssRes += math.Pow(ys[i]-predictions[i], 2)
-		ssTot += math.Pow(ys[i]-yMean, 2)
+		ssRes += weightAt(w, i) * math.Pow(ys[i]-predictions[i], 2)
+		ssTot += weightAt(w, i) * math.Pow(ys[i]-yMean, 2)
 	}
 	if ssTot > 0 {
 		r2 = 1 - ssRes/ssTot
 	}
-	mse = ssRes / float64(len(ys))
+	mse = ssRes / totalWeight
 	rmse = math.Sqrt(mse)
 	return r2, mse, rmse
 }
@@ -302,42 +552,105 @@ func (lr *LinearRegression) GetBias() float64 {
 }
 
 func (lr *LinearRegression) ImportModelFromString(modelStr string) error {
+	doc, err := parseLegacyModel(modelStr)
+	if err != nil {
+		return err
+	}
+	return lr.applyModelDocument(doc)
+}
+
+func parseLegacyModel(modelStr string) (*ModelDocument, error) {
 	lines := strings.Split(modelStr, "\n")
 	var (
-		biasPattern    = regexp.MustCompile(`^Bias:\s*([0-9.\-eE]+)`)
-		weightPattern  = regexp.MustCompile(`^W\d+:\s*([0-9.\-eE]+)`)
+		biasPattern   = regexp.MustCompile(`^Bias:\s*([0-9.\-eE]+)`)
+		weightPattern = regexp.MustCompile(`^W(\d+):\s*([0-9.\-eE]+)`)
+		headerPattern = regexp.MustCompile(`^(Training Time|Converged|Final Training Loss|Training R²|Training MSE|Training RMSE):\s*(\S+)`)
 	)
 	var (
-		bias    float64
-		weights []float64
+		bias      float64
+		weights   = make(map[int]float64)
 		foundBias bool
+		metrics   ModelMetrics
 	)
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if m := biasPattern.FindStringSubmatch(line); m != nil {
 			val, err := strconv.ParseFloat(m[1], 64)
 			if err != nil {
-				return fmt.Errorf("error parsing bias: %w", err)
+				return nil, fmt.Errorf("error parsing bias: %w", err)
 			}
 			bias = val
 			foundBias = true
 		} else if m := weightPattern.FindStringSubmatch(line); m != nil {
-			val, err := strconv.ParseFloat(m[1], 64)
+			idx, err := strconv.Atoi(m[1])
 			if err != nil {
-				return fmt.Errorf("error parsing weight: %w", err)
+				return nil, fmt.Errorf("error parsing weight index: %w", err)
+			}
+			if _, dup := weights[idx]; dup {
+				return nil, fmt.Errorf("weight W%d appears more than once", idx)
 			}
-			weights = append(weights, val)
+			val, err := strconv.ParseFloat(m[2], 64)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing weight: %w", err)
+			}
+			weights[idx] = val
+		} else if m := headerPattern.FindStringSubmatch(line); m != nil {
+			metrics.setLegacyField(m[1], m[2])
 		}
 	}
 	if !foundBias {
-		return fmt.Errorf("bias not found in model string")
+		return nil, fmt.Errorf("bias not found in model string")
 	}
 	if len(weights) == 0 {
-		return fmt.Errorf("no weights found in model string")
+		return nil, fmt.Errorf("no weights found in model string")
 	}
-	lr.Bias = bias
-	lr.Weights = make([]float64, len(weights))
-	copy(lr.Weights, weights)
-	return nil
+	ordered := make([]float64, len(weights))
+	for i := range ordered {
+		val, ok := weights[i]
+		if !ok {
+			return nil, fmt.Errorf("weight W%d is missing from model string", i)
+		}
+		ordered[i] = val
+	}
+
+	names := defaultFeatureNames(len(ordered))
+	doc := &ModelDocument{
+		Features: names,
+		Weights:  make(map[string]float64, len(ordered)),
+		Bias:     bias,
+		Metrics:  metrics,
+	}
+	for i, name := range names {
+		doc.Weights[name] = ordered[i]
+	}
+	return doc, nil
 }
 
+type TrainingSummary struct {
+	TrainingTime time.Duration
+	R2           float64
+	MSE          float64
+	RMSE         float64
+}
+
+func (lr *LinearRegression) ExportModelToString(summary TrainingSummary) string {
+	var sb strings.Builder
+	finalLoss := 0.0
+	if len(lr.TrainingLoss) > 0 {
+		finalLoss = lr.TrainingLoss[len(lr.TrainingLoss)-1]
+	}
+	sb.WriteString("=== Linear Regression Model Results ===\n")
+	fmt.Fprintf(&sb, "Training Time: %v\n", summary.TrainingTime)
+	fmt.Fprintf(&sb, "Converged: %v\n", lr.Converged)
+	fmt.Fprintf(&sb, "Final Training Loss: %.6f\n", finalLoss)
+	fmt.Fprintf(&sb, "Training R²: %.6f\n", summary.R2)
+	fmt.Fprintf(&sb, "Training MSE: %.6f\n", summary.MSE)
+	fmt.Fprintf(&sb, "Training RMSE: %.6f\n", summary.RMSE)
+	sb.WriteString("\nModel Parameters:\n")
+	fmt.Fprintf(&sb, "Bias: %.6f\n", lr.Bias)
+	sb.WriteString("\nWeights:\n")
+	for i, w := range lr.Weights {
+		fmt.Fprintf(&sb, "W%d: %.6f\n", i, w)
+	}
+	return sb.String()
+}
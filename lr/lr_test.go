@@ -0,0 +1,24 @@
+package lr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeNormalizationLargeOffset(t *testing.T) {
+	// Around 1e8 the weighted mean of squares minus the squared mean
+	// cancels to noise.
+	xs := [][]float64{{1e8 + 1}, {1e8 + 2}, {1e8 + 3}, {1e8 + 4}}
+	ys := []float64{1, 2, 3, 4}
+	w := []float64{1, 2, 2, 1}
+	model := New(1)
+	model.computeNormalization(xs, ys, w)
+	// Weighted mean 1e8 + 2.5; squared deviations 2.25, 0.25, 0.25, 2.25.
+	wantStd := math.Sqrt((2.25 + 2*0.25 + 2*0.25 + 2.25) / 6)
+	if !closeTo(model.xMeans[0], 1e8+2.5, 1e-15) || !closeTo(model.xStds[0], wantStd, 1e-7) {
+		t.Errorf("got mean %v and std %v, want %v and %v", model.xMeans[0], model.xStds[0], 1e8+2.5, wantStd)
+	}
+	if !closeTo(model.yMean, 2.5, 1e-15) || !closeTo(model.yStd, wantStd, 1e-12) {
+		t.Errorf("got target mean %v and std %v, want 2.5 and %v", model.yMean, model.yStd, wantStd)
+	}
+}
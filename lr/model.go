@@ -0,0 +1,263 @@
+package lr
+
+import (
+	"backend/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ModelSchemaVersion is bumped whenever a field of ModelDocument changes
+// meaning. Documents read from the legacy text format report version 0.
+const ModelSchemaVersion = 1
+
+type ModelDocument struct {
+	SchemaVersion int                 `json:"schema_version"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Features      []string            `json:"features"`
+	Weights       map[string]float64  `json:"weights"`
+	Bias          float64             `json:"bias"`
+	Normalization *NormalizationStats `json:"normalization,omitempty"`
+	Training      TrainingParams      `json:"training"`
+	Metrics       ModelMetrics        `json:"metrics"`
+	Uncertainty   *UncertaintyStats   `json:"uncertainty,omitempty"`
+	// FeatureSchema generated Features; nil in models saved before schemas
+	// were recorded.
+	FeatureSchema *utils.FeatureSchema `json:"feature_schema,omitempty"`
+}
+
+type NormalizationStats struct {
+	XMeans map[string]float64 `json:"x_means"`
+	XStds  map[string]float64 `json:"x_stds"`
+	YMean  float64            `json:"y_mean"`
+	YStd   float64            `json:"y_std"`
+}
+
+type TrainingParams struct {
+	Solver   string `json:"solver,omitempty"`
+	Weighted bool   `json:"weighted,omitempty"`
+	FitOptions
+}
+
+type ModelMetrics struct {
+	TrainingTime      string               `json:"training_time,omitempty"`
+	Converged         bool                 `json:"converged"`
+	FinalTrainingLoss float64              `json:"final_training_loss"`
+	EarlyStopping     *EarlyStoppingResult `json:"early_stopping,omitempty"`
+	R2                float64              `json:"r2"`
+	MSE               float64              `json:"mse"`
+	RMSE              float64              `json:"rmse"`
+}
+
+func (m *ModelMetrics) setLegacyField(name, value string) {
+	switch name {
+	case "Training Time":
+		m.TrainingTime = value
+	case "Converged":
+		m.Converged, _ = strconv.ParseBool(value)
+	case "Final Training Loss":
+		m.FinalTrainingLoss, _ = strconv.ParseFloat(value, 64)
+	case "Training R²":
+		m.R2, _ = strconv.ParseFloat(value, 64)
+	case "Training MSE":
+		m.MSE, _ = strconv.ParseFloat(value, 64)
+	case "Training RMSE":
+		m.RMSE, _ = strconv.ParseFloat(value, 64)
+	}
+}
+
+// defaultFeatureNames names the weights of a legacy text model, which only
+// stores W0..Wn, after the feature layout with that many features.
+func defaultFeatureNames(numFeatures int) []string {
+	for _, schema := range []*utils.FeatureSchema{utils.DefaultSchema(), utils.DefaultSchema().WithoutProtected()} {
+		if names := schema.Names(); len(names) == numFeatures {
+			return names
+		}
+	}
+	names := make([]string, numFeatures)
+	for i := range names {
+		names[i] = fmt.Sprintf("W%d", i)
+	}
+	return names
+}
+
+// ReadModelDocument decodes either the JSON model format or the legacy
+// "Model Parameters / Bias / W0..Wn" text format.
+func ReadModelDocument(data []byte) (*ModelDocument, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return parseLegacyModel(string(data))
+	}
+	var doc ModelDocument
+	if err := json.Unmarshal(trimmed, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing model JSON: %w", err)
+	}
+	if doc.SchemaVersion < 1 || doc.SchemaVersion > ModelSchemaVersion {
+		return nil, fmt.Errorf("unsupported model schema version %d (this build reads up to %d)", doc.SchemaVersion, ModelSchemaVersion)
+	}
+	return &doc, nil
+}
+
+func (lr *LinearRegression) ModelDocument(summary TrainingSummary) *ModelDocument {
+	names := lr.FeatureNames
+	if len(names) != len(lr.Weights) {
+		names = defaultFeatureNames(len(lr.Weights))
+	}
+	doc := &ModelDocument{
+		SchemaVersion: ModelSchemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Features:      names,
+		Weights:       make(map[string]float64, len(names)),
+		Bias:          lr.Bias,
+		Training:      lr.Params,
+		Uncertainty:   lr.Uncertainty,
+		FeatureSchema: lr.FeatureSchema,
+		Metrics: ModelMetrics{
+			Converged:     lr.Converged,
+			EarlyStopping: lr.EarlyStopping,
+			R2:            summary.R2,
+			MSE:           summary.MSE,
+			RMSE:          summary.RMSE,
+		},
+	}
+	if summary.TrainingTime > 0 {
+		doc.Metrics.TrainingTime = summary.TrainingTime.String()
+	}
+	if len(lr.TrainingLoss) > 0 {
+		doc.Metrics.FinalTrainingLoss = lr.TrainingLoss[len(lr.TrainingLoss)-1]
+	}
+	for i, name := range names {
+		doc.Weights[name] = lr.Weights[i]
+	}
+	if lr.hasNormalization() {
+		doc.Normalization = &NormalizationStats{
+			XMeans: make(map[string]float64, len(names)),
+			XStds:  make(map[string]float64, len(names)),
+			YMean:  lr.yMean,
+			YStd:   lr.yStd,
+		}
+		for i, name := range names {
+			doc.Normalization.XMeans[name] = lr.xMeans[i]
+			doc.Normalization.XStds[name] = lr.xStds[i]
+		}
+	}
+	return doc
+}
+
+func (lr *LinearRegression) ExportModel(summary TrainingSummary) ([]byte, error) {
+	return json.MarshalIndent(lr.ModelDocument(summary), "", "  ")
+}
+
+func (lr *LinearRegression) ImportModel(data []byte) error {
+	doc, err := ReadModelDocument(data)
+	if err != nil {
+		return err
+	}
+	return lr.applyModelDocument(doc)
+}
+
+func (lr *LinearRegression) hasNormalization() bool {
+	if len(lr.xStds) != len(lr.Weights) || len(lr.xMeans) != len(lr.Weights) {
+		return false
+	}
+	for _, std := range lr.xStds {
+		if std > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (lr *LinearRegression) applyModelDocument(doc *ModelDocument) error {
+	if len(doc.Features) == 0 {
+		return fmt.Errorf("model does not list any features")
+	}
+	if len(doc.Weights) != len(doc.Features) {
+		return fmt.Errorf("model has %d weights for %d features", len(doc.Weights), len(doc.Features))
+	}
+	weights := make([]float64, len(doc.Features))
+	for i, name := range doc.Features {
+		w, ok := doc.Weights[name]
+		if !ok {
+			return fmt.Errorf("model has no weight for feature %q", name)
+		}
+		weights[i] = w
+	}
+
+	xMeans := make([]float64, len(doc.Features))
+	xStds := make([]float64, len(doc.Features))
+	var yMean, yStd float64
+	if stats := doc.Normalization; stats != nil {
+		for i, name := range doc.Features {
+			mean, okMean := stats.XMeans[name]
+			std, okStd := stats.XStds[name]
+			if !okMean || !okStd {
+				return fmt.Errorf("model has no normalization statistics for feature %q", name)
+			}
+			xMeans[i], xStds[i] = mean, std
+		}
+		yMean, yStd = stats.YMean, stats.YStd
+	}
+
+	if schema := doc.FeatureSchema; schema != nil {
+		if err := schema.Check(); err != nil {
+			return fmt.Errorf("model feature schema: %w", err)
+		}
+		if names := schema.Names(); !slices.Equal(names, doc.Features) {
+			return fmt.Errorf("model feature schema generates %d features that do not match the %d listed", len(names), len(doc.Features))
+		}
+	}
+
+	if u := doc.Uncertainty; u != nil {
+		if doc.Normalization == nil {
+			return fmt.Errorf("model has uncertainty statistics but no normalization statistics")
+		}
+		if len(u.InverseGram) != len(doc.Features) {
+			return fmt.Errorf("model inverse Gram matrix has %d rows for %d features", len(u.InverseGram), len(doc.Features))
+		}
+		for i, row := range u.InverseGram {
+			if len(row) != len(doc.Features) {
+				return fmt.Errorf("model inverse Gram matrix row %d has %d columns for %d features", i, len(row), len(doc.Features))
+			}
+		}
+	}
+
+	lr.Weights = weights
+	lr.Bias = doc.Bias
+	lr.Uncertainty = doc.Uncertainty
+	lr.FeatureNames = append([]string(nil), doc.Features...)
+	lr.FeatureSchema = doc.FeatureSchema
+	lr.xMeans, lr.xStds = xMeans, xStds
+	lr.yMean, lr.yStd = yMean, yStd
+	lr.Params = doc.Training
+	if doc.Training.Seed != nil {
+		lr.Seed = *doc.Training.Seed
+	}
+	lr.Converged = doc.Metrics.Converged
+	lr.EarlyStopping = doc.Metrics.EarlyStopping
+	lr.TrainingLoss = nil
+	if doc.Metrics.FinalTrainingLoss != 0 {
+		lr.TrainingLoss = []float64{doc.Metrics.FinalTrainingLoss}
+	}
+	return nil
+}
+
+// ValidateFeatures checks that the model was trained on exactly the given
+// feature layout, so predictions never silently misalign weights and inputs.
+func (lr *LinearRegression) ValidateFeatures(names []string) error {
+	if len(lr.Weights) != len(names) {
+		return fmt.Errorf("model has %d weights but the feature extractor produces %d features; please retrain the model", len(lr.Weights), len(names))
+	}
+	if len(lr.FeatureNames) == 0 {
+		return nil
+	}
+	for i, name := range names {
+		if lr.FeatureNames[i] != name {
+			return fmt.Errorf("feature %d is %q in the model but %q in the feature extractor; please retrain the model", i, lr.FeatureNames[i], name)
+		}
+	}
+	return nil
+}
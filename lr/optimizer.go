@@ -0,0 +1,192 @@
+package lr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Optimizer turns a gradient into a parameter update. params holds the
+// standardized weights followed by the bias; Step updates it in place and may
+// keep per-parameter state between calls, so a fresh Optimizer is created for
+// every fit.
+type Optimizer interface {
+	Step(params, grads []float64, learningRate float64)
+}
+
+// OptimizerConfig selects and tunes an Optimizer. Zero fields take the usual
+// defaults (momentum 0.9, beta1 0.9, beta2 0.999, decay 0.9, epsilon 1e-8).
+type OptimizerConfig struct {
+	Name     string  `json:"name,omitempty"`
+	Momentum float64 `json:"momentum,omitempty"`
+	Beta1    float64 `json:"beta1,omitempty"`
+	Beta2    float64 `json:"beta2,omitempty"`
+	Decay    float64 `json:"decay,omitempty"`
+	Epsilon  float64 `json:"epsilon,omitempty"`
+}
+
+func (c OptimizerConfig) withDefaults() OptimizerConfig {
+	if c.Name == "" {
+		c.Name = "sgd"
+	}
+	if c.Momentum == 0 {
+		c.Momentum = 0.9
+	}
+	if c.Beta1 == 0 {
+		c.Beta1 = 0.9
+	}
+	if c.Beta2 == 0 {
+		c.Beta2 = 0.999
+	}
+	if c.Decay == 0 {
+		c.Decay = 0.9
+	}
+	if c.Epsilon == 0 {
+		c.Epsilon = 1e-8
+	}
+	return c
+}
+
+func (c OptimizerConfig) Validate() error {
+	c = c.withDefaults()
+	inUnit := func(v float64) bool { return v > 0 && v < 1 }
+	switch {
+	case !inUnit(c.Momentum):
+		return fmt.Errorf("momentum must be between 0 and 1, got %v", c.Momentum)
+	case !inUnit(c.Beta1) || !inUnit(c.Beta2):
+		return fmt.Errorf("Adam betas must be between 0 and 1, got %v and %v", c.Beta1, c.Beta2)
+	case !inUnit(c.Decay):
+		return fmt.Errorf("RMSProp decay must be between 0 and 1, got %v", c.Decay)
+	case c.Epsilon < 0:
+		return fmt.Errorf("epsilon must be positive, got %v", c.Epsilon)
+	}
+	_, err := c.New(0)
+	return err
+}
+
+// New returns the configured optimizer for numParams parameters.
+func (c OptimizerConfig) New(numParams int) (Optimizer, error) {
+	c = c.withDefaults()
+	switch c.Name {
+	case "sgd":
+		return sgd{}, nil
+	case "momentum", "nesterov":
+		return &momentum{beta: c.Momentum, nesterov: c.Name == "nesterov", velocity: make([]float64, numParams)}, nil
+	case "rmsprop":
+		return &rmsProp{decay: c.Decay, epsilon: c.Epsilon, meanSquare: make([]float64, numParams)}, nil
+	case "adam":
+		return &adam{beta1: c.Beta1, beta2: c.Beta2, epsilon: c.Epsilon, m: make([]float64, numParams), v: make([]float64, numParams)}, nil
+	}
+	return nil, fmt.Errorf("unknown optimizer %q (use sgd, momentum, nesterov, rmsprop or adam)", c.Name)
+}
+
+type sgd struct{}
+
+func (sgd) Step(params, grads []float64, learningRate float64) {
+	for i := range params {
+		params[i] -= learningRate * grads[i]
+	}
+}
+
+// momentum keeps an exponentially decaying sum of past gradients. The
+// Nesterov variant applies the look-ahead in the usual reformulated form, so
+// the gradient is still evaluated at the current parameters.
+type momentum struct {
+	beta     float64
+	nesterov bool
+	velocity []float64
+}
+
+func (o *momentum) Step(params, grads []float64, learningRate float64) {
+	for i := range params {
+		o.velocity[i] = o.beta*o.velocity[i] + grads[i]
+		update := o.velocity[i]
+		if o.nesterov {
+			update = grads[i] + o.beta*o.velocity[i]
+		}
+		params[i] -= learningRate * update
+	}
+}
+
+type rmsProp struct {
+	decay, epsilon float64
+	meanSquare     []float64
+}
+
+func (o *rmsProp) Step(params, grads []float64, learningRate float64) {
+	for i := range params {
+		o.meanSquare[i] = o.decay*o.meanSquare[i] + (1-o.decay)*grads[i]*grads[i]
+		params[i] -= learningRate * grads[i] / (math.Sqrt(o.meanSquare[i]) + o.epsilon)
+	}
+}
+
+type adam struct {
+	beta1, beta2, epsilon float64
+	m, v                  []float64
+	t                     int
+}
+
+func (o *adam) Step(params, grads []float64, learningRate float64) {
+	o.t++
+	correction1 := 1 - math.Pow(o.beta1, float64(o.t))
+	correction2 := 1 - math.Pow(o.beta2, float64(o.t))
+	for i := range params {
+		o.m[i] = o.beta1*o.m[i] + (1-o.beta1)*grads[i]
+		o.v[i] = o.beta2*o.v[i] + (1-o.beta2)*grads[i]*grads[i]
+		mHat := o.m[i] / correction1
+		vHat := o.v[i] / correction2
+		params[i] -= learningRate * mHat / (math.Sqrt(vHat) + o.epsilon)
+	}
+}
+
+// ScheduleConfig scales the base learning rate by epoch:
+//
+//	constant     1
+//	step         Gamma^floor(epoch/StepSize)   (defaults 0.5 every 1000 epochs)
+//	exponential  Gamma^epoch                   (default 0.999)
+//	cosine       (1+cos(π·epoch/epochs))/2
+//
+// The plateau decay controlled by Patience and DecayFactor applies on top.
+type ScheduleConfig struct {
+	Name     string  `json:"name,omitempty"`
+	StepSize int     `json:"step_size,omitempty"`
+	Gamma    float64 `json:"gamma,omitempty"`
+}
+
+func (s ScheduleConfig) Validate() error {
+	switch s.Name {
+	case "", "constant", "step", "exponential", "cosine":
+	default:
+		return fmt.Errorf("unknown learning rate schedule %q (use constant, step, exponential or cosine)", s.Name)
+	}
+	if s.StepSize < 0 {
+		return fmt.Errorf("schedule step size must be non-negative, got %d", s.StepSize)
+	}
+	if s.Gamma != 0 && !(s.Gamma > 0 && s.Gamma <= 1) {
+		return fmt.Errorf("schedule gamma must be in (0, 1], got %v", s.Gamma)
+	}
+	return nil
+}
+
+// Factor returns the multiplier applied to the base learning rate at epoch.
+func (s ScheduleConfig) Factor(epoch, epochs int) float64 {
+	switch s.Name {
+	case "step":
+		stepSize, gamma := s.StepSize, s.Gamma
+		if stepSize == 0 {
+			stepSize = 1000
+		}
+		if gamma == 0 {
+			gamma = 0.5
+		}
+		return math.Pow(gamma, float64(epoch/stepSize))
+	case "exponential":
+		gamma := s.Gamma
+		if gamma == 0 {
+			gamma = 0.999
+		}
+		return math.Pow(gamma, float64(epoch))
+	case "cosine":
+		return 0.5 * (1 + math.Cos(math.Pi*float64(epoch)/float64(epochs)))
+	}
+	return 1
+}
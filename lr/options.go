@@ -0,0 +1,131 @@
+package lr
+
+import (
+	"fmt"
+	"time"
+)
+
+// FitOptions configures gradient-descent training. The learning rate is
+// multiplied by DecayFactor whenever the training loss has not improved for
+// Patience consecutive loss checks, and training stops once it drops below
+// MinLearningRate or the loss drops below LossThreshold. BatchSize > 0
+// switches to shuffled mini-batches, one optimizer step per batch.
+//
+// A non-nil Seed re-draws the initial weights from that seed before training;
+// otherwise the weights drawn by New are used. The same seed drives the
+// mini-batch shuffling, and trained models always record it.
+//
+// FixedEpochs runs all Epochs: the loss threshold and the learning rate floor
+// no longer end training, and validation early stopping is rejected. Use it to
+// do a fixed amount of work, e.g. when measuring throughput.
+//
+// An L1 penalty is applied as a soft-threshold after each step, which needs
+// the one step size shared by every weight: it is rejected with the rmsprop
+// and adam optimizers, whose steps differ per weight.
+//
+// Training is silent unless Observers are given; Validation, when set, adds a
+// validation loss to every entry of the training history. SampleWeights, one
+// per training row, turn the loss and the normalization stats into weighted
+// averages.
+type FitOptions struct {
+	Seed            *int64              `json:"seed,omitempty"`
+	Epochs          int                 `json:"epochs,omitempty"`
+	LearningRate    float64             `json:"learning_rate,omitempty"`
+	Workers         int                 `json:"workers,omitempty"`
+	Regularization  Regularization      `json:"regularization"`
+	Patience        int                 `json:"patience,omitempty"`
+	DecayFactor     float64             `json:"decay_factor,omitempty"`
+	GradientClip    float64             `json:"gradient_clip,omitempty"`
+	LossThreshold   float64             `json:"loss_threshold,omitempty"`
+	MinLearningRate float64             `json:"min_learning_rate,omitempty"`
+	BatchSize       int                 `json:"batch_size,omitempty"`
+	Optimizer       OptimizerConfig     `json:"optimizer"`
+	Schedule        ScheduleConfig      `json:"schedule"`
+	EarlyStopping   EarlyStoppingConfig `json:"early_stopping"`
+	TimeBudget      time.Duration       `json:"time_budget,omitempty"`
+	FixedEpochs     bool                `json:"fixed_epochs,omitempty"`
+	SampleWeights   []float64           `json:"-"`
+	Validation      *ValidationData     `json:"-"`
+	Observers       []Observer          `json:"-"`
+}
+
+func DefaultFitOptions() FitOptions {
+	return FitOptions{
+		Epochs:          5000,
+		LearningRate:    0.01,
+		Workers:         1,
+		Patience:        15,
+		DecayFactor:     0.7,
+		GradientClip:    1.0,
+		LossThreshold:   1e-6,
+		MinLearningRate: 1e-8,
+		Optimizer:       OptimizerConfig{Name: "sgd"},
+		Schedule:        ScheduleConfig{Name: "constant"},
+	}
+}
+
+func (o FitOptions) Validate() error {
+	switch {
+	case o.Epochs < 1:
+		return fmt.Errorf("epochs must be at least 1, got %d", o.Epochs)
+	case !(o.LearningRate > 0):
+		return fmt.Errorf("learning rate must be positive, got %v", o.LearningRate)
+	case o.Workers < 1:
+		return fmt.Errorf("workers must be at least 1, got %d", o.Workers)
+	case o.Patience < 1:
+		return fmt.Errorf("patience must be at least 1, got %d", o.Patience)
+	case !(o.DecayFactor > 0 && o.DecayFactor <= 1):
+		return fmt.Errorf("decay factor must be in (0, 1], got %v", o.DecayFactor)
+	case o.GradientClip < 0:
+		return fmt.Errorf("gradient clip must be non-negative (0 disables it), got %v", o.GradientClip)
+	case o.LossThreshold < 0 || o.MinLearningRate < 0:
+		return fmt.Errorf("loss threshold and minimum learning rate must be non-negative")
+	case o.BatchSize < 0:
+		return fmt.Errorf("batch size must be non-negative (0 uses every row), got %d", o.BatchSize)
+	case o.TimeBudget < 0:
+		return fmt.Errorf("time budget must be non-negative (0 means unlimited), got %v", o.TimeBudget)
+	}
+	if v := o.Validation; v != nil && len(v.Xs) != len(v.Ys) {
+		return fmt.Errorf("validation xs and ys must have the same length")
+	}
+	if f := o.EarlyStopping.ValidationFraction; f < 0 || f >= 1 {
+		return fmt.Errorf("early stopping validation fraction must be in [0, 1), got %v", f)
+	}
+	if o.EarlyStopping.Patience < 0 || o.EarlyStopping.MinDelta < 0 {
+		return fmt.Errorf("early stopping patience and min delta must be non-negative")
+	}
+	if o.FixedEpochs && o.EarlyStopping.Patience > 0 {
+		return fmt.Errorf("fixed epochs cannot be combined with early stopping")
+	}
+	if err := o.Optimizer.Validate(); err != nil {
+		return err
+	}
+	if name := o.Optimizer.withDefaults().Name; o.Regularization.l1() > 0 && (name == "rmsprop" || name == "adam") {
+		return fmt.Errorf("an L1 penalty needs a fixed step size and cannot be used with the %s optimizer", name)
+	}
+	if err := o.Schedule.Validate(); err != nil {
+		return err
+	}
+	return o.Regularization.Validate()
+}
+
+// EarlyStoppingConfig stops training once the validation loss has not
+// improved by more than MinDelta for Patience consecutive epochs, then
+// restores the weights from the best epoch. Without FitOptions.Validation,
+// ValidationFraction (default 0.1) of the training rows is held out, chosen
+// by the seed, and only the rest is used for fitting. Patience 0 disables it.
+type EarlyStoppingConfig struct {
+	Patience           int     `json:"patience,omitempty"`
+	MinDelta           float64 `json:"min_delta,omitempty"`
+	ValidationFraction float64 `json:"validation_fraction,omitempty"`
+}
+
+// EarlyStoppingResult reports where the restored weights came from. Stopped
+// is false when training ran out of epochs (or converged) before patience
+// ran out; the best epoch's weights are restored either way.
+type EarlyStoppingResult struct {
+	BestEpoch          int     `json:"best_epoch"`
+	BestValidationLoss float64 `json:"best_validation_loss"`
+	Stopped            bool    `json:"stopped"`
+	StoppedEpoch       int     `json:"stopped_epoch,omitempty"`
+}
@@ -0,0 +1,66 @@
+package lr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Regularization penalizes the standardized weights with the elastic-net term
+// Lambda * (L1Ratio*|w|₁ + (1-L1Ratio)/2*|w|²). The bias is never penalized.
+type Regularization struct {
+	Lambda  float64 `json:"lambda"`
+	L1Ratio float64 `json:"l1_ratio"`
+}
+
+func Ridge(lambda float64) Regularization {
+	return Regularization{Lambda: lambda, L1Ratio: 0}
+}
+
+func Lasso(lambda float64) Regularization {
+	return Regularization{Lambda: lambda, L1Ratio: 1}
+}
+
+func ElasticNet(lambda, l1Ratio float64) Regularization {
+	return Regularization{Lambda: lambda, L1Ratio: l1Ratio}
+}
+
+func (r Regularization) Validate() error {
+	if r.Lambda < 0 || math.IsNaN(r.Lambda) || math.IsInf(r.Lambda, 0) {
+		return fmt.Errorf("regularization strength must be a non-negative number, got %v", r.Lambda)
+	}
+	if r.L1Ratio < 0 || r.L1Ratio > 1 || math.IsNaN(r.L1Ratio) {
+		return fmt.Errorf("L1 ratio must be between 0 and 1, got %v", r.L1Ratio)
+	}
+	return nil
+}
+
+func (r Regularization) l1() float64 {
+	return r.Lambda * r.L1Ratio
+}
+
+func (r Regularization) l2() float64 {
+	return r.Lambda * (1 - r.L1Ratio)
+}
+
+// addGradient adds the smooth L2 part of the penalty to the data gradient.
+func (r Regularization) addGradient(grad, weights []float64) {
+	l2 := r.l2()
+	if l2 == 0 {
+		return
+	}
+	for j := range grad {
+		grad[j] += l2 * weights[j]
+	}
+}
+
+// proximal applies the soft-thresholding step for the L1 part of the penalty
+// after a gradient step of size stepSize.
+func (r Regularization) proximal(weights []float64, stepSize float64) {
+	threshold := stepSize * r.l1()
+	if threshold == 0 {
+		return
+	}
+	for j, w := range weights {
+		weights[j] = math.Copysign(math.Max(math.Abs(w)-threshold, 0), w)
+	}
+}
@@ -0,0 +1,237 @@
+package lr
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// RegressionMetrics summarizes the residuals y - prediction of a set of rows.
+// With sample weights every metric but Count and MaxError is a weighted
+// average or weighted median, like EvaluateWeighted. MAPE is a percentage
+// over the rows whose target is not zero, and AdjustedR2 is omitted when
+// there are not more rows than features + 1.
+type RegressionMetrics struct {
+	Count        int      `json:"count"`
+	R2           float64  `json:"r2"`
+	AdjustedR2   *float64 `json:"adjusted_r2,omitempty"`
+	MSE          float64  `json:"mse"`
+	RMSE         float64  `json:"rmse"`
+	MAE          float64  `json:"mae"`
+	MedianAE     float64  `json:"median_ae"`
+	MAPE         float64  `json:"mape"`
+	MaxError     float64  `json:"max_error"`
+	MeanResidual float64  `json:"mean_residual"`
+}
+
+type ResidualQuantile struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+// HistogramBin counts rows, regardless of their sample weights.
+type HistogramBin struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+}
+
+type GroupMetrics struct {
+	Group string `json:"group"`
+	RegressionMetrics
+}
+
+type EvaluationReport struct {
+	RegressionMetrics
+	ResidualQuantiles []ResidualQuantile `json:"residual_quantiles"`
+	ResidualHistogram []HistogramBin     `json:"residual_histogram"`
+	// Groups maps a column name to the metrics of each of its values,
+	// worst RMSE first.
+	Groups map[string][]GroupMetrics `json:"groups,omitempty"`
+}
+
+var reportQuantiles = []float64{0.01, 0.05, 0.25, 0.5, 0.75, 0.95, 0.99}
+
+// EvaluationReport scores the model on xs and ys, weighting every row by
+// sampleWeights (nil weighs all rows equally). groups optionally maps a
+// column name to one label per row (e.g. "PROGRAMA" to every row's program),
+// and bins sets the number of residual histogram bins.
+func (lr *LinearRegression) EvaluationReport(xs [][]float64, ys, sampleWeights []float64, groups map[string][]string, bins int) (*EvaluationReport, error) {
+	if len(xs) != len(ys) {
+		return nil, fmt.Errorf("xs and ys must have the same length")
+	}
+	if err := validateSampleWeights(sampleWeights, len(xs)); err != nil {
+		return nil, err
+	}
+	if len(xs) == 0 {
+		return nil, fmt.Errorf("no rows to evaluate")
+	}
+	if bins < 1 {
+		return nil, fmt.Errorf("histogram needs at least one bin, got %d", bins)
+	}
+	for column, labels := range groups {
+		if len(labels) != len(xs) {
+			return nil, fmt.Errorf("group column %s has %d labels for %d rows", column, len(labels), len(xs))
+		}
+	}
+
+	predictions := lr.PredictBatch(xs)
+	all := make([]int, len(xs))
+	for i := range all {
+		all[i] = i
+	}
+	report := &EvaluationReport{RegressionMetrics: lr.regressionMetrics(ys, predictions, sampleWeights, all)}
+
+	residuals := make([]float64, len(ys))
+	for i := range ys {
+		residuals[i] = ys[i] - predictions[i]
+	}
+	weights := slices.Clone(sampleWeights)
+	sortWeighted(residuals, weights)
+	for _, q := range reportQuantiles {
+		report.ResidualQuantiles = append(report.ResidualQuantiles, ResidualQuantile{Quantile: q, Value: quantileWeighted(residuals, weights, q)})
+	}
+	report.ResidualHistogram = histogram(residuals, bins)
+
+	if len(groups) > 0 {
+		report.Groups = make(map[string][]GroupMetrics, len(groups))
+	}
+	for column, labels := range groups {
+		rowsByGroup := make(map[string][]int)
+		for i, label := range labels {
+			rowsByGroup[label] = append(rowsByGroup[label], i)
+		}
+		metrics := make([]GroupMetrics, 0, len(rowsByGroup))
+		for label, rows := range rowsByGroup {
+			metrics = append(metrics, GroupMetrics{Group: label, RegressionMetrics: lr.regressionMetrics(ys, predictions, sampleWeights, rows)})
+		}
+		sort.Slice(metrics, func(a, b int) bool {
+			if metrics[a].RMSE != metrics[b].RMSE {
+				return metrics[a].RMSE > metrics[b].RMSE
+			}
+			return metrics[a].Group < metrics[b].Group
+		})
+		report.Groups[column] = metrics
+	}
+	return report, nil
+}
+
+func (lr *LinearRegression) regressionMetrics(ys, predictions, w []float64, rows []int) RegressionMetrics {
+	m := RegressionMetrics{Count: len(rows)}
+	var total float64
+	for _, i := range rows {
+		total += weightAt(w, i)
+	}
+	if total == 0 {
+		return m
+	}
+	n := float64(len(rows))
+	var yMean float64
+	for _, i := range rows {
+		yMean += weightAt(w, i) * ys[i] / total
+	}
+	absErrors := make([]float64, len(rows))
+	weights := subsetWeights(w, rows)
+	var ssRes, ssTot, apeSum, apeWeight float64
+	for k, i := range rows {
+		weight := weightAt(w, i)
+		residual := ys[i] - predictions[i]
+		ssRes += weight * residual * residual
+		ssTot += weight * (ys[i] - yMean) * (ys[i] - yMean)
+		absErrors[k] = math.Abs(residual)
+		m.MAE += weight * absErrors[k] / total
+		m.MeanResidual += weight * residual / total
+		m.MaxError = math.Max(m.MaxError, absErrors[k])
+		if ys[i] != 0 {
+			apeSum += weight * absErrors[k] / math.Abs(ys[i])
+			apeWeight += weight
+		}
+	}
+	m.MSE = ssRes / total
+	m.RMSE = math.Sqrt(m.MSE)
+	if ssTot > 0 {
+		m.R2 = 1 - ssRes/ssTot
+	}
+	if p := float64(len(lr.Weights)); n > p+1 {
+		adjusted := 1 - (1-m.R2)*(n-1)/(n-p-1)
+		m.AdjustedR2 = &adjusted
+	}
+	if apeWeight > 0 {
+		m.MAPE = 100 * apeSum / apeWeight
+	}
+	sortWeighted(absErrors, weights)
+	m.MedianAE = quantileWeighted(absErrors, weights, 0.5)
+	return m
+}
+
+// sortWeighted sorts values in place, permuting weights (if not nil) along.
+func sortWeighted(values, weights []float64) {
+	if weights == nil {
+		sort.Float64s(values)
+		return
+	}
+	sort.Sort(weightedValues{values, weights})
+}
+
+type weightedValues struct{ values, weights []float64 }
+
+func (v weightedValues) Len() int           { return len(v.values) }
+func (v weightedValues) Less(i, j int) bool { return v.values[i] < v.values[j] }
+func (v weightedValues) Swap(i, j int) {
+	v.values[i], v.values[j] = v.values[j], v.values[i]
+	v.weights[i], v.weights[j] = v.weights[j], v.weights[i]
+}
+
+// quantileWeighted is quantileSorted without weights; with weights it
+// returns the first value at which the cumulative weight reaches q of the
+// total.
+func quantileWeighted(sorted, weights []float64, q float64) float64 {
+	if weights == nil {
+		return quantileSorted(sorted, q)
+	}
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	var cumulative float64
+	for i, w := range weights {
+		cumulative += w
+		if cumulative >= q*total {
+			return sorted[i]
+		}
+	}
+	return sorted[len(sorted)-1]
+}
+
+// quantileSorted interpolates linearly between the closest ranks.
+func quantileSorted(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (pos-float64(lo))*(sorted[hi]-sorted[lo])
+}
+
+// histogram splits the range of sorted into equal-width bins; the last bin
+// includes its upper edge.
+func histogram(sorted []float64, bins int) []HistogramBin {
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if hi == lo {
+		return []HistogramBin{{Lower: lo, Upper: hi, Count: len(sorted)}}
+	}
+	width := (hi - lo) / float64(bins)
+	result := make([]HistogramBin, bins)
+	for b := range result {
+		result[b].Lower = lo + float64(b)*width
+		result[b].Upper = lo + float64(b+1)*width
+	}
+	result[bins-1].Upper = hi
+	for _, v := range sorted {
+		b := min(int((v-lo)/width), bins-1)
+		result[b].Count++
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package lr
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// SearchSpace lists the values tried for each hyperparameter. Empty lists
+// keep the value from the base options.
+type SearchSpace struct {
+	LearningRates []float64
+	Epochs        []int
+	Lambdas       []float64
+	L1Ratios      []float64
+}
+
+// Grid returns every combination of the listed values.
+func (s SearchSpace) Grid(base FitOptions) []FitOptions {
+	candidates := []FitOptions{base}
+	expand := func(n int, set func(o *FitOptions, i int)) {
+		if n == 0 {
+			return
+		}
+		next := make([]FitOptions, 0, len(candidates)*n)
+		for _, c := range candidates {
+			for i := 0; i < n; i++ {
+				o := c
+				set(&o, i)
+				next = append(next, o)
+			}
+		}
+		candidates = next
+	}
+	expand(len(s.LearningRates), func(o *FitOptions, i int) { o.LearningRate = s.LearningRates[i] })
+	expand(len(s.Epochs), func(o *FitOptions, i int) { o.Epochs = s.Epochs[i] })
+	expand(len(s.Lambdas), func(o *FitOptions, i int) { o.Regularization.Lambda = s.Lambdas[i] })
+	expand(len(s.L1Ratios), func(o *FitOptions, i int) { o.Regularization.L1Ratio = s.L1Ratios[i] })
+	return candidates
+}
+
+// Random returns n distinct combinations drawn from the grid, or the whole
+// grid when it has n or fewer entries.
+func (s SearchSpace) Random(base FitOptions, n int, seed int64) []FitOptions {
+	grid := s.Grid(base)
+	if n >= len(grid) {
+		return grid
+	}
+	rng := rand.New(rand.NewSource(seed))
+	picked := make([]FitOptions, n)
+	for i, idx := range rng.Perm(len(grid))[:n] {
+		picked[i] = grid[idx]
+	}
+	return picked
+}
+
+type SearchResult struct {
+	Options         FitOptions             `json:"options"`
+	CrossValidation *CrossValidationResult `json:"cross_validation,omitempty"`
+	Score           float64                `json:"score"`
+	Error           string                 `json:"error,omitempty"`
+}
+
+// metricScore returns a value where lower is always better, so R² is negated.
+func metricScore(metric string, m Metrics) (float64, error) {
+	switch metric {
+	case "r2":
+		return -m.R2, nil
+	case "mse":
+		return m.MSE, nil
+	case "rmse":
+		return m.RMSE, nil
+	case "mae":
+		return m.MAE, nil
+	}
+	return 0, fmt.Errorf("unknown metric %q (use r2, mse, rmse or mae)", metric)
+}
+
+// Search cross-validates every candidate on the same splits, running up to
+// parallelism fits at once, and returns the results ordered best first by the
+// mean of metric across folds. Candidates whose fit fails are listed last.
+func Search(xs [][]float64, ys []float64, candidates []FitOptions, splits []Split, metric string, parallelism int) ([]SearchResult, error) {
+	if _, err := metricScore(metric, Metrics{}); err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no candidate configurations to search")
+	}
+	if len(xs) == 0 {
+		return nil, fmt.Errorf("no training data to search on")
+	}
+	numFeatures := len(xs[0])
+	parallelism = max(1, parallelism)
+
+	results := make([]SearchResult, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(parallelism, len(candidates)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				opts := candidates[i]
+				results[i].Options = opts
+				cv, err := CrossValidate(xs, ys, splits, func(trainXs [][]float64, trainYs []float64) (*LinearRegression, error) {
+					model := New(numFeatures)
+					err := model.FitWithOptions(trainXs, trainYs, opts)
+					return model, err
+				})
+				if err != nil {
+					results[i].Error = err.Error()
+					results[i].Score = math.Inf(1)
+					continue
+				}
+				score, _ := metricScore(metric, cv.Mean)
+				if math.IsNaN(score) || math.IsInf(score, 0) {
+					results[i].Error = "training diverged"
+					results[i].Score = math.Inf(1)
+					continue
+				}
+				results[i].CrossValidation = cv
+				results[i].Score = score
+			}
+		}()
+	}
+	for i := range candidates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(results, func(a, b int) bool { return results[a].Score < results[b].Score })
+	for i := range results {
+		switch {
+		case results[i].Error != "":
+			results[i].Score = 0
+		case metric == "r2":
+			results[i].Score = -results[i].Score
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,188 @@
+package lr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Columns whose remaining norm after pivoting falls below this fraction of the
+// largest pivot are treated as linear combinations of the columns already chosen.
+const rankTolerance = 1e-10
+
+type SolverReport struct {
+	Rank              int
+	NumFeatures       int
+	DependentFeatures []int
+}
+
+func (r *SolverReport) RankDeficient() bool {
+	return r.Rank < r.NumFeatures
+}
+
+// FitExact computes the ordinary least-squares weights with a column-pivoted
+// Householder QR decomposition of the standardized, centered design matrix.
+// Features that are constant or linearly dependent on the others (for example
+// one dummy of every one-hot block, which is collinear with the bias) get a
+// zero weight and are listed in the returned report.
+func (lr *LinearRegression) FitExact(xs [][]float64, ys []float64) (*SolverReport, error) {
+	return lr.FitExactWeighted(xs, ys, nil)
+}
+
+// FitExactWeighted solves the weighted least squares problem: centering uses
+// the weighted means and every row is scaled by the square root of its
+// sample weight before the QR decomposition. nil weights every row equally.
+func (lr *LinearRegression) FitExactWeighted(xs [][]float64, ys, sampleWeights []float64) (*SolverReport, error) {
+	if err := lr.validateTrainingData(xs, ys); err != nil {
+		return nil, err
+	}
+	if err := validateSampleWeights(sampleWeights, len(xs)); err != nil {
+		return nil, err
+	}
+	w := normalizedSampleWeights(sampleWeights)
+	n := len(xs)
+	numFeatures := len(lr.Weights)
+	lr.computeNormalization(xs, ys, w)
+
+	cols := make([][]float64, numFeatures)
+	for j := range cols {
+		cols[j] = make([]float64, n)
+		for i := 0; i < n; i++ {
+			cols[j][i] = math.Sqrt(weightAt(w, i)) * (xs[i][j] - lr.xMeans[j]) / lr.xStds[j]
+		}
+	}
+	rhs := make([]float64, n)
+	for i, y := range ys {
+		rhs[i] = math.Sqrt(weightAt(w, i)) * (y - lr.yMean)
+	}
+
+	coef, rank, err := solveLeastSquaresQR(cols, rhs)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SolverReport{Rank: rank, NumFeatures: numFeatures}
+	lr.Bias = lr.yMean
+	for j := 0; j < numFeatures; j++ {
+		if math.IsNaN(coef[j]) {
+			report.DependentFeatures = append(report.DependentFeatures, j)
+			lr.Weights[j] = 0
+			continue
+		}
+		lr.Weights[j] = coef[j] / lr.xStds[j]
+		lr.Bias -= lr.Weights[j] * lr.xMeans[j]
+	}
+
+	lr.Params = TrainingParams{Solver: "qr", Weighted: sampleWeights != nil}
+	lr.History = nil
+	lr.Converged = true
+	lr.TrainingLoss = []float64{lr.normalizedLoss(xs, ys, w)}
+	lr.computeUncertainty(xs, ys, w, report.DependentFeatures)
+	return report, nil
+}
+
+// normalizedLoss reports the mean squared error in standardized target units,
+// matching the loss Fit records during gradient descent.
+func (lr *LinearRegression) normalizedLoss(xs [][]float64, ys, w []float64) float64 {
+	var sse, totalWeight float64
+	for i, x := range xs {
+		err := lr.Predict(x) - ys[i]
+		sse += weightAt(w, i) * err * err
+		totalWeight += weightAt(w, i)
+	}
+	mse := sse / totalWeight
+	if lr.yStd > 1e-8 {
+		mse /= lr.yStd * lr.yStd
+	}
+	return mse
+}
+
+// solveLeastSquaresQR minimizes ||A·x - b|| where A is given column by column.
+// Both cols and b are overwritten. Coefficients of dependent columns are
+// returned as NaN so callers can tell them apart from genuine zeros.
+func solveLeastSquaresQR(cols [][]float64, b []float64) ([]float64, int, error) {
+	p := len(cols)
+	if p == 0 {
+		return nil, 0, nil
+	}
+	n := len(b)
+	perm := make([]int, p)
+	for j := range perm {
+		perm[j] = j
+	}
+
+	steps := min(n, p)
+	rank := 0
+	var firstPivot float64
+	for k := 0; k < steps; k++ {
+		best, bestNorm := k, -1.0
+		for j := k; j < p; j++ {
+			var sq float64
+			for _, v := range cols[j][k:] {
+				sq += v * v
+			}
+			if sq > bestNorm {
+				best, bestNorm = j, sq
+			}
+		}
+		norm := math.Sqrt(bestNorm)
+		if k == 0 {
+			firstPivot = norm
+		}
+		if norm == 0 || norm <= rankTolerance*firstPivot {
+			break
+		}
+		cols[k], cols[best] = cols[best], cols[k]
+		perm[k], perm[best] = perm[best], perm[k]
+
+		// Householder reflector v = a + sign(a_k)·||a||·e_k, applied as
+		// I - 2vvᵀ/(vᵀv) to the trailing columns and the right-hand side.
+		a := cols[k]
+		alpha := -math.Copysign(norm, a[k])
+		a[k] -= alpha
+		vtv := 0.0
+		for _, v := range a[k:] {
+			vtv += v * v
+		}
+		if vtv > 0 {
+			for j := k + 1; j < p; j++ {
+				applyReflector(a[k:], cols[j][k:], vtv)
+			}
+			applyReflector(a[k:], b[k:], vtv)
+		}
+		// The column now only needs to hold R's diagonal entry.
+		a[k] = alpha
+		rank++
+	}
+
+	z := make([]float64, rank)
+	for k := rank - 1; k >= 0; k-- {
+		sum := b[k]
+		for j := k + 1; j < rank; j++ {
+			sum -= cols[j][k] * z[j]
+		}
+		z[k] = sum / cols[k][k]
+		if math.IsNaN(z[k]) || math.IsInf(z[k], 0) {
+			return nil, 0, fmt.Errorf("least-squares solve is numerically unstable at column %d", perm[k])
+		}
+	}
+
+	coef := make([]float64, p)
+	for j := range coef {
+		coef[j] = math.NaN()
+	}
+	for k := 0; k < rank; k++ {
+		coef[perm[k]] = z[k]
+	}
+	return coef, rank, nil
+}
+
+func applyReflector(v, x []float64, vtv float64) {
+	var dot float64
+	for i := range v {
+		dot += v[i] * x[i]
+	}
+	scale := 2 * dot / vtv
+	for i := range v {
+		x[i] -= scale * v[i]
+	}
+}
@@ -0,0 +1,116 @@
+package lr
+
+import (
+	"math"
+	"testing"
+)
+
+func closeTo(got, want, tol float64) bool {
+	return math.Abs(got-want) <= tol*math.Max(1, math.Abs(want))
+}
+
+func TestSolveLeastSquaresQR(t *testing.T) {
+	tests := []struct {
+		name     string
+		cols     [][]float64
+		b        []float64
+		wantRank int
+		want     []float64
+	}{
+		{
+			name:     "intercept and slope",
+			cols:     [][]float64{{1, 1, 1}, {1, 2, 3}},
+			b:        []float64{1, 2, 2},
+			wantRank: 2,
+			want:     []float64{2.0 / 3, 0.5},
+		},
+		{
+			name:     "exact fit",
+			cols:     [][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+			b:        []float64{4, -2, 0.5},
+			wantRank: 3,
+			want:     []float64{4, -2, 0.5},
+		},
+	}
+	for _, tt := range tests {
+		coef, rank, err := solveLeastSquaresQR(tt.cols, tt.b)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if rank != tt.wantRank {
+			t.Errorf("%s: rank %d, want %d", tt.name, rank, tt.wantRank)
+		}
+		for j := range tt.want {
+			if !closeTo(coef[j], tt.want[j], 1e-12) {
+				t.Errorf("%s: coefficient %d is %v, want %v", tt.name, j, coef[j], tt.want[j])
+			}
+		}
+	}
+}
+
+func TestSolveLeastSquaresQRAliasedColumn(t *testing.T) {
+	// The third column is twice the second, so only two are identifiable.
+	cols := [][]float64{{1, 1, 1, 1}, {1, 2, 3, 4}, {2, 4, 6, 8}}
+	b := []float64{2, 3, 5, 6}
+	coef, rank, err := solveLeastSquaresQR(cols, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank != 2 {
+		t.Fatalf("rank %d, want 2", rank)
+	}
+	if math.IsNaN(coef[1]) == math.IsNaN(coef[2]) || math.IsNaN(coef[0]) {
+		t.Fatalf("want exactly one of the aliased columns dropped, got %v", coef)
+	}
+	// Whichever column is kept, the fitted line is y = 0.5 + 1.4x.
+	slope := coef[1]
+	if math.IsNaN(slope) {
+		slope = 2 * coef[2]
+	}
+	if !closeTo(coef[0], 0.5, 1e-12) || !closeTo(slope, 1.4, 1e-12) {
+		t.Errorf("got intercept %v and slope %v, want 0.5 and 1.4", coef[0], slope)
+	}
+}
+
+func TestFitExact(t *testing.T) {
+	model := New(1)
+	report, err := model.FitExact([][]float64{{1}, {2}, {3}}, []float64{1, 2, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.RankDeficient() {
+		t.Errorf("full-rank design reported as rank %d of %d", report.Rank, report.NumFeatures)
+	}
+	if !closeTo(model.Weights[0], 0.5, 1e-12) || !closeTo(model.Bias, 2.0/3, 1e-12) {
+		t.Errorf("got weight %v and bias %v, want 0.5 and 2/3", model.Weights[0], model.Bias)
+	}
+}
+
+func TestFitExactReportsDependentFeatures(t *testing.T) {
+	xs := [][]float64{{1, 2, 0}, {2, 4, 1}, {3, 6, 0}, {4, 8, 1}, {5, 10, 1}}
+	ys := []float64{4, 8, 10, 14, 17}
+	model := New(3)
+	report, err := model.FitExact(xs, ys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rank != 2 || !report.RankDeficient() {
+		t.Errorf("rank %d of %d, want 2 of 3", report.Rank, report.NumFeatures)
+	}
+	if len(report.DependentFeatures) != 1 || report.DependentFeatures[0] > 1 {
+		t.Fatalf("dependent features %v, want one of the first two", report.DependentFeatures)
+	}
+	if w := model.Weights[report.DependentFeatures[0]]; w != 0 {
+		t.Errorf("dependent feature has weight %v, want 0", w)
+	}
+	// The fit itself does not depend on which aliased column was dropped.
+	full := New(2)
+	if _, err := full.FitExact([][]float64{{1, 0}, {2, 1}, {3, 0}, {4, 1}, {5, 1}}, ys); err != nil {
+		t.Fatal(err)
+	}
+	for i, x := range xs {
+		if got, want := model.Predict(x), full.Predict([]float64{x[0], x[2]}); !closeTo(got, want, 1e-9) {
+			t.Errorf("row %d: prediction %v, want %v", i, got, want)
+		}
+	}
+}
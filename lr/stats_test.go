@@ -0,0 +1,40 @@
+package lr
+
+import "testing"
+
+func TestStudentTQuantile(t *testing.T) {
+	// Published two-sided critical values of the t distribution.
+	tests := []struct {
+		p, df, want float64
+	}{
+		{0.975, 1, 12.706204736},
+		{0.975, 2, 4.302652730},
+		{0.975, 10, 2.228138852},
+		{0.975, 30, 2.042272456},
+		{0.95, 5, 2.015048373},
+		{0.995, 30, 2.749995654},
+		{0.025, 10, -2.228138852},
+	}
+	for _, tt := range tests {
+		if got := studentTQuantile(tt.p, tt.df); !closeTo(got, tt.want, 1e-8) {
+			t.Errorf("studentTQuantile(%v, %v) = %.9f, want %.9f", tt.p, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestStudentTCDF(t *testing.T) {
+	tests := []struct {
+		t, df, want float64
+	}{
+		{0, 3, 0.5},
+		{1, 1, 0.75},
+		{-1, 1, 0.25},
+		{2.228138852, 10, 0.975},
+		{-2.015048373, 5, 0.05},
+	}
+	for _, tt := range tests {
+		if got := studentTCDF(tt.t, tt.df); !closeTo(got, tt.want, 1e-9) {
+			t.Errorf("studentTCDF(%v, %v) = %.10f, want %.10f", tt.t, tt.df, got, tt.want)
+		}
+	}
+}
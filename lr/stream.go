@@ -0,0 +1,253 @@
+package lr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+)
+
+// DefaultStreamBatchSize is the mini-batch size FitStream uses when
+// FitOptions.BatchSize is 0.
+const DefaultStreamBatchSize = 1024
+
+// RowReader yields training rows in order. Next copies up to len(xs) rows
+// into the caller's buffers (each xs[i] already has one slot per feature)
+// and returns how many it filled; it returns io.EOF, possibly together with
+// a final partial chunk, once the data is exhausted. Readers that also
+// implement io.Closer are closed after every pass.
+type RowReader interface {
+	Next(xs [][]float64, ys []float64) (int, error)
+}
+
+// RowSource opens a new RowReader positioned at the first row. FitStream
+// makes several passes over the data, so it must be callable repeatedly,
+// e.g. by reopening a file.
+type RowSource func() (RowReader, error)
+
+// forEachChunk makes one pass over source, calling fn with every chunk read,
+// and stops with ctx's error once it is done. The buffers are reused between
+// calls.
+func forEachChunk(ctx context.Context, source RowSource, xs [][]float64, ys []float64, fn func(xs [][]float64, ys []float64)) (err error) {
+	reader, err := source()
+	if err != nil {
+		return err
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer func() {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}()
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := reader.Next(xs, ys)
+		if n > 0 {
+			fn(xs[:n], ys[:n])
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func chunkBuffers(rows, numFeatures int) ([][]float64, []float64) {
+	xs := make([][]float64, rows)
+	for i := range xs {
+		xs[i] = make([]float64, numFeatures)
+	}
+	return xs, make([]float64, rows)
+}
+
+// FitStream trains like FitContext without holding the data in memory. A
+// first pass computes the normalization stats with Welford's algorithm; each
+// epoch then reads the rows again in chunks of BatchSize (default
+// DefaultStreamBatchSize), one optimizer step per chunk, and a final pass
+// computes the uncertainty statistics. Only a few chunk buffers are ever
+// allocated.
+//
+// Rows are visited in the order the source yields them, so data sorted by
+// e.g. academic term should be shuffled beforehand. Early stopping needs
+// opts.Validation, since a stream cannot be split into a holdout set.
+func (lr *LinearRegression) FitStream(ctx context.Context, source RowSource, opts FitOptions) error {
+	if opts.EarlyStopping.Patience > 0 && opts.Validation == nil {
+		return fmt.Errorf("early stopping on a stream needs FitOptions.Validation")
+	}
+	if opts.SampleWeights != nil {
+		return fmt.Errorf("sample weights are not supported when streaming")
+	}
+	opts, err := lr.prepareFit(opts)
+	if err != nil {
+		return err
+	}
+	batchSize := opts.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultStreamBatchSize
+	}
+	numFeatures := len(lr.Weights)
+	rawXs, rawYs := chunkBuffers(batchSize, numFeatures)
+
+	n, err := lr.streamNormalization(ctx, source, rawXs, rawYs)
+	if err != nil {
+		return fmt.Errorf("normalization pass: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("training data must not be empty")
+	}
+
+	err = lr.descend(ctx, opts, newStreamBatches(lr, source, rawXs, rawYs))
+	var interrupted *InterruptedError
+	if err != nil && !errors.As(err, &interrupted) {
+		return err
+	}
+	lr.restoreScale()
+	// Like FitContext, an interrupted fit still finishes the model, so this
+	// pass does not stop with ctx.
+	acc := lr.newUncertaintyAccumulator()
+	if uerr := forEachChunk(context.Background(), source, rawXs, rawYs, func(xs [][]float64, ys []float64) {
+		for i := range xs {
+			acc.add(xs[i], ys[i], 1)
+		}
+	}); uerr != nil {
+		return fmt.Errorf("uncertainty pass: %w", uerr)
+	}
+	lr.Uncertainty = acc.finish()
+	return err
+}
+
+// welford accumulates a weighted mean and the weighted sum of squared
+// deviations from it in one pass (West's update of Welford's algorithm),
+// without the cancellation of subtracting the squared mean from the mean
+// square.
+type welford struct {
+	weight, mean, m2 float64
+}
+
+func (a *welford) add(x, weight float64) {
+	if weight == 0 {
+		return
+	}
+	a.weight += weight
+	delta := x - a.mean
+	a.mean += delta * weight / a.weight
+	a.m2 += weight * delta * (x - a.mean)
+}
+
+// variance returns the population variance, never below 0.
+func (a *welford) variance() float64 {
+	if a.weight == 0 {
+		return 0
+	}
+	return math.Max(a.m2/a.weight, 0)
+}
+
+// streamNormalization sets the normalization stats from one pass over
+// source, matching computeNormalization (population standard deviations,
+// floored at 1e-4 for the features), and returns the row count.
+func (lr *LinearRegression) streamNormalization(ctx context.Context, source RowSource, rawXs [][]float64, rawYs []float64) (int, error) {
+	numFeatures := len(lr.Weights)
+	xAcc := make([]welford, numFeatures)
+	var yAcc welford
+	n := 0
+	err := forEachChunk(ctx, source, rawXs, rawYs, func(xs [][]float64, ys []float64) {
+		for i, x := range xs {
+			n++
+			for j := range xAcc {
+				xAcc[j].add(x[j], 1)
+			}
+			yAcc.add(ys[i], 1)
+		}
+	})
+	if err != nil || n == 0 {
+		return n, err
+	}
+	lr.xMeans = make([]float64, numFeatures)
+	lr.xStds = make([]float64, numFeatures)
+	for j := range xAcc {
+		lr.xMeans[j] = xAcc[j].mean
+		lr.xStds[j] = math.Sqrt(math.Max(xAcc[j].variance(), 1e-8))
+	}
+	lr.yMean = yAcc.mean
+	lr.yStd = math.Sqrt(yAcc.variance())
+	return n, nil
+}
+
+// streamBatches re-reads the source on every epoch and standardizes each
+// chunk into a reused buffer. Passes are not cancelled midway: descend checks
+// its context between epochs.
+type streamBatches struct {
+	lr     *LinearRegression
+	source RowSource
+	rawXs  [][]float64
+	rawYs  []float64
+	xsNorm [][]float64
+	ysNorm []float64
+	rows   []int
+}
+
+func newStreamBatches(lr *LinearRegression, source RowSource, rawXs [][]float64, rawYs []float64) *streamBatches {
+	xsNorm, ysNorm := chunkBuffers(len(rawXs), len(lr.Weights))
+	rows := make([]int, len(rawXs))
+	for i := range rows {
+		rows[i] = i
+	}
+	return &streamBatches{lr: lr, source: source, rawXs: rawXs, rawYs: rawYs, xsNorm: xsNorm, ysNorm: ysNorm, rows: rows}
+}
+
+func (b *streamBatches) pass(fn func(xs [][]float64, ys []float64, rows []int)) error {
+	return forEachChunk(context.Background(), b.source, b.rawXs, b.rawYs, func(xs [][]float64, ys []float64) {
+		xsNorm, ysNorm := b.xsNorm[:len(xs)], b.ysNorm[:len(ys)]
+		b.lr.normalizeInto(xs, ys, xsNorm, ysNorm)
+		fn(xsNorm, ysNorm, b.rows[:len(xs)])
+	})
+}
+
+func (b *streamBatches) epoch(_ *rand.Rand, step func(xs [][]float64, ys, w []float64, rows []int)) error {
+	return b.pass(func(xs [][]float64, ys []float64, rows []int) {
+		step(xs, ys, nil, rows)
+	})
+}
+
+func (b *streamBatches) loss(lr *LinearRegression) (float64, error) {
+	var total float64
+	var n int
+	err := b.pass(func(xs [][]float64, ys []float64, _ []int) {
+		total += lr.calculateLossMultivariate(xs, ys, nil) * float64(len(xs))
+		n += len(xs)
+	})
+	if n == 0 {
+		return 0, err
+	}
+	return total / float64(n), err
+}
+
+// EvaluateStream is Evaluate over the rows of source, in one pass that stops
+// once ctx is done.
+func (lr *LinearRegression) EvaluateStream(ctx context.Context, source RowSource) (r2, mse, rmse float64, err error) {
+	var ssRes float64
+	var yAcc welford
+	xs, ys := chunkBuffers(DefaultStreamBatchSize, len(lr.Weights))
+	err = forEachChunk(ctx, source, xs, ys, func(xs [][]float64, ys []float64) {
+		for i, x := range xs {
+			residual := ys[i] - lr.Predict(x)
+			ssRes += residual * residual
+			yAcc.add(ys[i], 1)
+		}
+	})
+	if err != nil || yAcc.weight == 0 {
+		return 0, 0, 0, err
+	}
+	if ssTot := yAcc.m2; ssTot > 0 {
+		r2 = 1 - ssRes/ssTot
+	}
+	mse = ssRes / yAcc.weight
+	return r2, mse, math.Sqrt(mse), nil
+}
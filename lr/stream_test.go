@@ -0,0 +1,53 @@
+package lr
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+// sliceReader yields rows from memory, chunk by chunk.
+type sliceReader struct {
+	xs   [][]float64
+	ys   []float64
+	next int
+}
+
+func (r *sliceReader) Next(xs [][]float64, ys []float64) (int, error) {
+	n := 0
+	for n < len(xs) && r.next < len(r.ys) {
+		copy(xs[n], r.xs[r.next])
+		ys[n] = r.ys[r.next]
+		n++
+		r.next++
+	}
+	if r.next == len(r.ys) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func sliceSource(xs [][]float64, ys []float64) RowSource {
+	return func() (RowReader, error) { return &sliceReader{xs: xs, ys: ys}, nil }
+}
+
+func TestEvaluateStreamMatchesEvaluateForLargeTargets(t *testing.T) {
+	// Targets around 1e9 with a spread of a few units: summing y² loses every
+	// significant digit of the total sum of squares.
+	xs, ys := syntheticData(5000, 3, 2)
+	for i := range ys {
+		ys[i] += 1e9
+	}
+	model := New(3)
+	if _, err := model.FitExact(xs, ys); err != nil {
+		t.Fatal(err)
+	}
+	wantR2, wantMSE, _ := model.Evaluate(xs, ys)
+	r2, mse, _, err := model.EvaluateStream(context.Background(), sliceSource(xs, ys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !closeTo(r2, wantR2, 1e-7) || !closeTo(mse, wantMSE, 1e-9) {
+		t.Errorf("EvaluateStream gave R² %v and MSE %v, want %v and %v", r2, mse, wantR2, wantMSE)
+	}
+}
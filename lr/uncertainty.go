@@ -0,0 +1,240 @@
+package lr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var ErrNoUncertainty = errors.New("model has no uncertainty statistics; retrain it to enable intervals")
+
+// UncertaintyStats holds what is needed for OLS-style intervals. InverseGram
+// is the (pseudo-)inverse of CᵀC, where C is the training design matrix after
+// centering and scaling each feature with the model's normalization stats;
+// rows and columns of linearly dependent features are zero.
+type UncertaintyStats struct {
+	ResidualVariance float64     `json:"residual_variance"`
+	DegreesOfFreedom int         `json:"degrees_of_freedom"`
+	NumSamples       int         `json:"num_samples"`
+	InverseGram      [][]float64 `json:"inverse_gram"`
+}
+
+func (lr *LinearRegression) standardizedRow(x []float64) []float64 {
+	c := make([]float64, len(x))
+	for j := range x {
+		if lr.xStds[j] > 1e-8 {
+			c[j] = (x[j] - lr.xMeans[j]) / lr.xStds[j]
+		}
+	}
+	return c
+}
+
+// computeUncertainty runs after a fit, once Weights, Bias and the
+// normalization stats are final. With sample weights (scaled to a mean of 1)
+// it gives the weighted least squares statistics, and the residual variance
+// is that of an observation of weight 1. dependent lists features the solver
+// already fixed at zero, so the statistics use the same basis as the weights;
+// other dependencies are resolved by pseudoInverseSPD.
+func (lr *LinearRegression) computeUncertainty(xs [][]float64, ys, w []float64, dependent []int) {
+	acc := lr.newUncertaintyAccumulator()
+	for i, x := range xs {
+		acc.add(x, ys[i], weightAt(w, i))
+	}
+	acc.exclude(dependent)
+	lr.Uncertainty = acc.finish()
+}
+
+// uncertaintyAccumulator collects the Gram matrix and residuals one row at a
+// time, so streamed fits can compute the same statistics in a single pass.
+type uncertaintyAccumulator struct {
+	lr   *LinearRegression
+	gram [][]float64
+	ssr  float64
+	n    int
+}
+
+func (lr *LinearRegression) newUncertaintyAccumulator() *uncertaintyAccumulator {
+	p := len(lr.Weights)
+	gram := make([][]float64, p)
+	for j := range gram {
+		gram[j] = make([]float64, p)
+	}
+	return &uncertaintyAccumulator{lr: lr, gram: gram}
+}
+
+func (a *uncertaintyAccumulator) add(x []float64, y, weight float64) {
+	c := a.lr.standardizedRow(x)
+	for j := range c {
+		if c[j] == 0 {
+			continue
+		}
+		for k := j; k < len(c); k++ {
+			a.gram[j][k] += weight * c[j] * c[k]
+		}
+	}
+	err := y - a.lr.Predict(x)
+	a.ssr += weight * err * err
+	a.n++
+}
+
+// exclude drops the given features from the Gram matrix before inversion.
+func (a *uncertaintyAccumulator) exclude(features []int) {
+	for _, j := range features {
+		for k := range a.gram {
+			a.gram[j][k], a.gram[k][j] = 0, 0
+		}
+	}
+}
+
+// finish returns nil when there are too few rows for a residual variance.
+func (a *uncertaintyAccumulator) finish() *UncertaintyStats {
+	p := len(a.gram)
+	for j := 0; j < p; j++ {
+		for k := 0; k < j; k++ {
+			a.gram[j][k] = a.gram[k][j]
+		}
+	}
+	inverse, rank := pseudoInverseSPD(a.gram)
+	dof := a.n - rank - 1
+	if dof <= 0 {
+		return nil
+	}
+	return &UncertaintyStats{
+		ResidualVariance: a.ssr / float64(dof),
+		DegreesOfFreedom: dof,
+		NumSamples:       a.n,
+		InverseGram:      inverse,
+	}
+}
+
+// pseudoInverseSPD inverts a symmetric positive semi-definite matrix with a
+// diagonally pivoted Cholesky factorization, leaving zeros for the rows and
+// columns that are numerically dependent on the ones already factored.
+func pseudoInverseSPD(a [][]float64) ([][]float64, int) {
+	p := len(a)
+	work := make([][]float64, p)
+	for i := range a {
+		work[i] = append([]float64(nil), a[i]...)
+	}
+	perm := make([]int, p)
+	for i := range perm {
+		perm[i] = i
+	}
+
+	var maxDiag float64
+	for i := 0; i < p; i++ {
+		maxDiag = math.Max(maxDiag, work[i][i])
+	}
+	// Pivots are squared norms here, but forming the Gram matrix squares the
+	// condition number too: round-off leaves exactly dependent columns with
+	// pivots near machine epsilon times the largest one rather than zero, far
+	// above the squared QR tolerance.
+	tol := rankTolerance * maxDiag
+
+	rank := 0
+	for k := 0; k < p; k++ {
+		best := k
+		for i := k + 1; i < p; i++ {
+			if work[i][i] > work[best][best] {
+				best = i
+			}
+		}
+		if work[best][best] <= tol || work[best][best] <= 0 {
+			break
+		}
+		work[k], work[best] = work[best], work[k]
+		for i := range work {
+			work[i][k], work[i][best] = work[i][best], work[i][k]
+		}
+		perm[k], perm[best] = perm[best], perm[k]
+
+		work[k][k] = math.Sqrt(work[k][k])
+		for i := k + 1; i < p; i++ {
+			work[i][k] /= work[k][k]
+		}
+		for i := k + 1; i < p; i++ {
+			for j := k + 1; j <= i; j++ {
+				work[i][j] -= work[i][k] * work[j][k]
+				work[j][i] = work[i][j]
+			}
+		}
+		rank++
+	}
+
+	// Invert the leading rank×rank lower-triangular factor L and form
+	// (LLᵀ)⁻¹ = L⁻ᵀL⁻¹.
+	linv := make([][]float64, rank)
+	for i := range linv {
+		linv[i] = make([]float64, rank)
+		linv[i][i] = 1 / work[i][i]
+		for j := 0; j < i; j++ {
+			var sum float64
+			for k := j; k < i; k++ {
+				sum += work[i][k] * linv[k][j]
+			}
+			linv[i][j] = -sum / work[i][i]
+		}
+	}
+	inverse := make([][]float64, p)
+	for i := range inverse {
+		inverse[i] = make([]float64, p)
+	}
+	for a := 0; a < rank; a++ {
+		for b := 0; b <= a; b++ {
+			var sum float64
+			for k := a; k < rank; k++ {
+				sum += linv[k][a] * linv[k][b]
+			}
+			inverse[perm[a]][perm[b]] = sum
+			inverse[perm[b]][perm[a]] = sum
+		}
+	}
+	return inverse, rank
+}
+
+func (lr *LinearRegression) intervalHalfWidth(x []float64, level float64, includeNoise bool) (float64, error) {
+	if lr.Uncertainty == nil || !lr.hasNormalization() {
+		return 0, ErrNoUncertainty
+	}
+	if len(x) != len(lr.Weights) {
+		return 0, fmt.Errorf("expected %d features, got %d", len(lr.Weights), len(x))
+	}
+	if !(level > 0 && level < 1) {
+		return 0, fmt.Errorf("confidence level must be between 0 and 1, got %v", level)
+	}
+	u := lr.Uncertainty
+	c := lr.standardizedRow(x)
+	leverage := 1 / float64(u.NumSamples)
+	for j := range c {
+		for k := range c {
+			leverage += c[j] * u.InverseGram[j][k] * c[k]
+		}
+	}
+	variance := u.ResidualVariance * leverage
+	if includeNoise {
+		variance += u.ResidualVariance
+	}
+	t := studentTQuantile(1-(1-level)/2, float64(u.DegreesOfFreedom))
+	return t * math.Sqrt(variance), nil
+}
+
+// ConfidenceInterval bounds the expected value of the target for x.
+func (lr *LinearRegression) ConfidenceInterval(x []float64, level float64) (lower, upper float64, err error) {
+	half, err := lr.intervalHalfWidth(x, level, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	prediction := lr.Predict(x)
+	return prediction - half, prediction + half, nil
+}
+
+// PredictionInterval bounds an individual observation of the target for x,
+// adding the residual noise to the uncertainty of the fitted mean.
+func (lr *LinearRegression) PredictionInterval(x []float64, level float64) (lower, upper float64, err error) {
+	half, err := lr.intervalHalfWidth(x, level, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	prediction := lr.Predict(x)
+	return prediction - half, prediction + half, nil
+}
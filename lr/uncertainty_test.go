@@ -0,0 +1,87 @@
+package lr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPseudoInverseSPD(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        [][]float64
+		wantRank int
+		want     [][]float64
+	}{
+		{
+			name:     "invertible",
+			a:        [][]float64{{2, 1}, {1, 1}},
+			wantRank: 2,
+			want:     [][]float64{{1, -1}, {-1, 2}},
+		},
+		{
+			name:     "zero row",
+			a:        [][]float64{{4, 0}, {0, 0}},
+			wantRank: 1,
+			want:     [][]float64{{0.25, 0}, {0, 0}},
+		},
+		{
+			// The second and third columns are the same, so only one of them
+			// is factored and the other is left at zero.
+			name:     "aliased columns",
+			a:        [][]float64{{2, 0, 0}, {0, 1, 1}, {0, 1, 1}},
+			wantRank: 2,
+		},
+	}
+	for _, tt := range tests {
+		inverse, rank := pseudoInverseSPD(tt.a)
+		if rank != tt.wantRank {
+			t.Errorf("%s: rank %d, want %d", tt.name, rank, tt.wantRank)
+		}
+		for i := range tt.want {
+			for j := range tt.want[i] {
+				if !closeTo(inverse[i][j], tt.want[i][j], 1e-12) {
+					t.Errorf("%s: inverse[%d][%d] = %v, want %v", tt.name, i, j, inverse[i][j], tt.want[i][j])
+				}
+			}
+		}
+	}
+}
+
+func TestIntervalsOfExactFit(t *testing.T) {
+	// y = 2/3 + x/2 leaves residuals -1/6, 1/3, -1/6: one degree of freedom
+	// and a residual variance of 1/6. At the mean x = 2 the leverage is 1/n.
+	model := New(1)
+	if _, err := model.FitExact([][]float64{{1}, {2}, {3}}, []float64{1, 2, 2}); err != nil {
+		t.Fatal(err)
+	}
+	u := model.Uncertainty
+	if u == nil {
+		t.Fatal("exact fit has no uncertainty statistics")
+	}
+	if u.DegreesOfFreedom != 1 || !closeTo(u.ResidualVariance, 1.0/6, 1e-12) {
+		t.Errorf("got %d degrees of freedom and residual variance %v, want 1 and 1/6", u.DegreesOfFreedom, u.ResidualVariance)
+	}
+	const t975 = 12.706204736
+	x := []float64{2}
+	lower, upper, err := model.ConfidenceInterval(x, 0.95)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := t975 * math.Sqrt(1.0/6/3); !closeTo(upper-model.Predict(x), want, 1e-8) || !closeTo(model.Predict(x)-lower, want, 1e-8) {
+		t.Errorf("confidence interval [%v, %v], want %v ± %v", lower, upper, model.Predict(x), want)
+	}
+	lower, upper, err = model.PredictionInterval(x, 0.95)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := t975 * math.Sqrt(1.0/6*(1+1.0/3)); !closeTo((upper-lower)/2, want, 1e-8) {
+		t.Errorf("prediction interval half-width %v, want %v", (upper-lower)/2, want)
+	}
+}
+
+func TestIntervalsNeedUncertainty(t *testing.T) {
+	model := New(1)
+	if _, _, err := model.PredictionInterval([]float64{1}, 0.95); err != ErrNoUncertainty {
+		t.Errorf("got %v, want ErrNoUncertainty", err)
+	}
+}
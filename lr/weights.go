@@ -0,0 +1,63 @@
+package lr
+
+import (
+	"fmt"
+	"math"
+)
+
+// validateSampleWeights accepts nil (every row weighs 1) or one finite,
+// non-negative weight per row with a positive total.
+func validateSampleWeights(w []float64, n int) error {
+	if w == nil {
+		return nil
+	}
+	if len(w) != n {
+		return fmt.Errorf("expected %d sample weights, got %d", n, len(w))
+	}
+	var total float64
+	for i, v := range w {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("sample weight %d must be finite and non-negative, got %v", i, v)
+		}
+		total += v
+	}
+	if total == 0 {
+		return fmt.Errorf("sample weights must not all be zero")
+	}
+	return nil
+}
+
+// normalizedSampleWeights rescales w to a mean of 1. The fit does not change,
+// but the residual variance then refers to an observation of weight 1.
+func normalizedSampleWeights(w []float64) []float64 {
+	if w == nil {
+		return nil
+	}
+	var total float64
+	for _, v := range w {
+		total += v
+	}
+	scaled := make([]float64, len(w))
+	for i, v := range w {
+		scaled[i] = v * float64(len(w)) / total
+	}
+	return scaled
+}
+
+func weightAt(w []float64, i int) float64 {
+	if w == nil {
+		return 1
+	}
+	return w[i]
+}
+
+func subsetWeights(w []float64, idx []int) []float64 {
+	if w == nil {
+		return nil
+	}
+	sub := make([]float64, len(idx))
+	for i, j := range idx {
+		sub[i] = w[j]
+	}
+	return sub
+}
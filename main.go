@@ -4,17 +4,21 @@ import (
 	"backend/lr"
 	"backend/utils"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
-	"math"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 )
 
-var modelData string
+var currentModel *lr.LinearRegression
 var modelMutex sync.RWMutex
+var strictInput bool
 
 func enableCORS(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -22,6 +26,37 @@ func enableCORS(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
 }
 
+// strictRequested reports whether a request should be validated with
+// the model's feature schema; ?strict=true|false overrides the -strict flag.
+func strictRequested(r *http.Request) bool {
+	if v, err := strconv.ParseBool(r.URL.Query().Get("strict")); err == nil {
+		return v
+	}
+	return strictInput
+}
+
+// intervalRequested reads ?interval=prediction|confidence and an optional
+// ?level (default 0.95) for the bounds returned next to the prediction.
+func intervalRequested(r *http.Request) (string, float64, error) {
+	query := r.URL.Query()
+	interval := query.Get("interval")
+	level := 0.95
+	if raw := query.Get("level"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v <= 0 || v >= 1 {
+			return "", 0, fmt.Errorf("level must be a number between 0 and 1, got %q", raw)
+		}
+		level = v
+		if interval == "" {
+			interval = "prediction"
+		}
+	}
+	if interval != "" && interval != "prediction" && interval != "confidence" {
+		return "", 0, fmt.Errorf("interval must be %q or %q, got %q", "prediction", "confidence", interval)
+	}
+	return interval, level, nil
+}
+
 func predictHandler(w http.ResponseWriter, r *http.Request) {
 	enableCORS(w, r)
 	
@@ -42,8 +77,21 @@ func predictHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	type PredictionResult struct {
-		Prediction float64 `json:"prediction"`
-		Error      string  `json:"error,omitempty"`
+		Prediction float64            `json:"prediction"`
+		Error      string             `json:"error,omitempty"`
+		Invalid    []utils.FieldError `json:"errors,omitempty"`
+		Explain    *predictionExplanation
+		Lower      float64
+		Upper      float64
+		// Status overrides 400 Bad Request for errors not caused by the input.
+		Status int
+	}
+	strict := strictRequested(r)
+	explain, _ := strconv.ParseBool(r.URL.Query().Get("explain"))
+	interval, level, err := intervalRequested(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	resultChan := make(chan PredictionResult, 1)
@@ -51,70 +99,181 @@ func predictHandler(w http.ResponseWriter, r *http.Request) {
 	go func() {
 		defer close(resultChan)
 
-		features, err := utils.ParseStudentDataToFeatures(body)
-		if err != nil {
-			resultChan <- PredictionResult{Error: "Invalid JSON or feature extraction failed: " + err.Error()}
-			return
-		}
-
-		model := lr.New(utils.GetExpectedFeatureCount())
-
 		modelMutex.RLock()
-		currentModelData := modelData
+		model := currentModel
 		modelMutex.RUnlock()
 
-		err = model.ImportModelFromString(currentModelData)
-		if err != nil {
-			resultChan <- PredictionResult{Error: "Failed to load model: " + err.Error()}
+		features, err := model.FeatureSchema.ParseJSON(body, strict)
+		var verr *utils.ValidationError
+		if errors.As(err, &verr) {
+			resultChan <- PredictionResult{Error: "Invalid student data", Invalid: verr.Errors}
 			return
 		}
-
-		expectedWeights := len(model.GetWeights())
-		fmt.Println(features)
-		if len(features) != expectedWeights {
-			resultChan <- PredictionResult{Error: fmt.Sprintf("Feature length mismatch: expected %d, got %d. Model was trained with %d features but current feature extraction produces %d features. Please retrain the model.", expectedWeights, len(features), expectedWeights, len(features))}
+		if err != nil {
+			resultChan <- PredictionResult{Error: "Invalid JSON or feature extraction failed: " + err.Error()}
 			return
 		}
 
 		prediction := model.Predict(features)
-		roundedPrediction := math.Round(prediction*100) / 100
-		resultChan <- PredictionResult{Prediction: roundedPrediction}
+		result := PredictionResult{Prediction: roundPrediction(prediction)}
+		if explain {
+			result.Explain = explainPrediction(model, features)
+		}
+		if interval != "" {
+			bounds := model.PredictionInterval
+			if interval == "confidence" {
+				bounds = model.ConfidenceInterval
+			}
+			lower, upper, err := bounds(features, level)
+			if errors.Is(err, lr.ErrNoUncertainty) {
+				resultChan <- PredictionResult{Error: "The served model cannot compute intervals: " + err.Error(), Status: http.StatusConflict}
+				return
+			}
+			if err != nil {
+				resultChan <- PredictionResult{Error: "Failed to compute interval: " + err.Error()}
+				return
+			}
+			result.Lower, result.Upper = roundPrediction(lower), roundPrediction(upper)
+		}
+		resultChan <- result
 	}()
 
 	result := <-resultChan
 
 	w.Header().Set("Content-Type", "application/json")
-	if result.Error != "" {
-		w.WriteHeader(http.StatusBadRequest)
+	if len(result.Invalid) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]any{"error": result.Error, "errors": result.Invalid})
+	} else if result.Error != "" {
+		status := http.StatusBadRequest
+		if result.Status != 0 {
+			status = result.Status
+		}
+		w.WriteHeader(status)
 		json.NewEncoder(w).Encode(map[string]string{"error": result.Error})
 	} else {
-		json.NewEncoder(w).Encode(map[string]float64{"prediction": result.Prediction})
+		response := map[string]any{"prediction": result.Prediction}
+		if result.Explain != nil {
+			response["explanation"] = result.Explain
+		}
+		if interval != "" {
+			response["lower"] = result.Lower
+			response["upper"] = result.Upper
+			response["interval"] = interval
+			response["level"] = level
+		}
+		json.NewEncoder(w).Encode(response)
 	}
 }
 
-func loadModelData(filename string) error {
+func loadModel(filename string) (*lr.LinearRegression, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	
-	modelMutex.Lock()
-	modelData = string(data)
-	modelMutex.Unlock()
-	
-	return nil
+
+	model := lr.New(utils.DefaultSchema().Count())
+	if err := model.ImportModel(data); err != nil {
+		return nil, err
+	}
+	if model.FeatureSchema == nil {
+		model.FeatureSchema = legacySchema(model)
+	}
+	if err := model.ValidateFeatures(model.FeatureSchema.Names()); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// legacySchema returns the schema of a model saved before schemas were
+// recorded: the built-in one, without the protected columns if the model
+// has that many weights. Such models may name a faculty with the trailing
+// space the old hard-coded vocabulary carried, so their feature names are
+// trimmed to match.
+func legacySchema(model *lr.LinearRegression) *utils.FeatureSchema {
+	for i, name := range model.FeatureNames {
+		model.FeatureNames[i] = strings.TrimSpace(name)
+	}
+	schema := utils.DefaultSchema()
+	if withoutProtected := schema.WithoutProtected(); len(model.Weights) == withoutProtected.Count() {
+		return withoutProtected
+	}
+	return schema
 }
 
 func main() {
-	err := loadModelData("values.txt")
+	if len(os.Args) > 1 && os.Args[1] == "train" {
+		if err := runTrain(os.Args[2:]); err != nil {
+			log.Fatalf("Training failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "search" {
+		if err := runSearch(os.Args[2:]); err != nil {
+			log.Fatalf("Search failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "evaluate" {
+		if err := runEvaluate(os.Args[2:]); err != nil {
+			log.Fatalf("Evaluation failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fairness" {
+		if err := runFairness(os.Args[2:]); err != nil {
+			log.Fatalf("Fairness audit failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		if err := runSchema(os.Args[2:]); err != nil {
+			log.Fatalf("Schema failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	modelPath := flag.String("model", "model.json", "model file in the JSON or legacy text format")
+	adminToken := flag.String("admin-token", os.Getenv("MODEL_ADMIN_TOKEN"), "bearer token for POST /admin/model/reload (defaults to $MODEL_ADMIN_TOKEN; empty disables the endpoint)")
+	maxBatch := flag.Int("max-batch", 10000, "maximum number of records accepted by POST /predict/batch")
+	reloadInterval := flag.Duration("reload-interval", 0, "how often to check the model file for changes and reload it (0 disables polling)")
+	flag.BoolVar(&strictInput, "strict", false, "reject records with unparsable, missing, unknown or out-of-range fields instead of coercing them to 0")
+	flag.Parse()
+
+	if *maxBatch < 1 {
+		flag.Usage()
+		log.Fatalf("-max-batch must be at least 1, got %d", *maxBatch)
+	}
+	model, err := loadModel(*modelPath)
 	if err != nil {
 		log.Fatalf("Failed to load model: %v", err)
 	}
+	modelMutex.Lock()
+	currentModel = model
+	modelMutex.Unlock()
 	fmt.Println("Model loaded successfully.")
+	if model.Uncertainty == nil {
+		log.Printf("Model %s has no uncertainty statistics; /predict answers interval requests with 409 Conflict until it is retrained", *modelPath)
+	}
+
+	reloader := newModelReloader(*modelPath)
+	go reloader.watchSignals()
+	if *reloadInterval > 0 {
+		go reloader.pollModTime(*reloadInterval)
+	}
 
 	http.HandleFunc("/predict", predictHandler)
+	http.HandleFunc("/predict/batch", batchPredictHandler(*maxBatch))
+	http.HandleFunc("/predict/csv", csvPredictHandler)
+	http.HandleFunc("/admin/model/reload", reloader.handler(*adminToken))
 
 	fmt.Println("Server running on http://localhost:8080")
-	fmt.Printf("Expected feature count: %d\n", utils.GetExpectedFeatureCount())
+	fmt.Printf("Expected feature count: %d\n", model.FeatureSchema.Count())
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
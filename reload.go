@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// modelReloader swaps a freshly parsed and validated model into currentModel.
+// A file that fails to load is reported and the previous model keeps serving.
+type modelReloader struct {
+	path string
+
+	mu          sync.Mutex
+	lastModTime time.Time
+}
+
+func newModelReloader(path string) *modelReloader {
+	r := &modelReloader{path: path}
+	if info, err := os.Stat(path); err == nil {
+		r.lastModTime = info.ModTime()
+	}
+	return r
+}
+
+func (r *modelReloader) reload(reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if info, err := os.Stat(r.path); err == nil {
+		r.lastModTime = info.ModTime()
+	}
+	model, err := loadModel(r.path)
+	if err != nil {
+		log.Printf("Model reload (%s) failed, keeping the current model: %v", reason, err)
+		return err
+	}
+
+	modelMutex.Lock()
+	currentModel = model
+	modelMutex.Unlock()
+	log.Printf("Model reloaded from %s (%s)", r.path, reason)
+	return nil
+}
+
+func (r *modelReloader) watchSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		r.reload("SIGHUP")
+	}
+}
+
+func (r *modelReloader) pollModTime(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		info, err := os.Stat(r.path)
+		if err != nil {
+			continue
+		}
+		r.mu.Lock()
+		changed := !info.ModTime().Equal(r.lastModTime)
+		r.mu.Unlock()
+		if changed {
+			r.reload("file changed")
+		}
+	}
+}
+
+func (r *modelReloader) handler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if token == "" {
+			http.Error(w, "Model reload endpoint is disabled", http.StatusForbidden)
+			return
+		}
+		provided, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := r.reload("admin endpoint"); err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to reload model: " + err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "reloaded"})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"backend/lr"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func parseFloatList(value string) ([]float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var values []float64
+	for _, part := range strings.Split(value, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in list %q", part, value)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func parseIntList(value string) ([]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var values []int
+	for _, part := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q in list %q", part, value)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+type searchReport struct {
+	CreatedAt  time.Time         `json:"created_at"`
+	Metric     string            `json:"metric"`
+	Folds      int               `json:"folds"`
+	GroupBy    string            `json:"group_by,omitempty"`
+	TimeBy     string            `json:"time_by,omitempty"`
+	Seed       int64             `json:"seed"`
+	Candidates int               `json:"candidates"`
+	Results    []lr.SearchResult `json:"results"`
+}
+
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	dataPath := fs.String("data", "", "CSV file with one column per schema column plus the target column")
+	target := fs.String("target", "", "name of the CSV column to predict")
+	outPath := fs.String("out", "best_model.json", "where to write the best model, refit on all rows; a .json extension selects the versioned JSON format")
+	reportPath := fs.String("report", "search_report.json", "where to write the ranked search results")
+	opts := lr.DefaultFitOptions()
+	registerFitFlags(fs, &opts)
+	lrs := fs.String("lrs", "0.001,0.01,0.1", "comma-separated learning rates to try")
+	epochGrid := fs.String("epoch-grid", "", "comma-separated epoch counts to try (default: -epochs)")
+	lambdas := fs.String("lambdas", "0", "comma-separated regularization strengths to try")
+	l1Ratios := fs.String("l1-ratios", "", "comma-separated L1 ratios to try (default: -l1-ratio)")
+	random := fs.Int("random", 0, "evaluate this many random configurations from the grid instead of all of them (0 searches the full grid)")
+	folds := fs.Int("cv", 5, "number of cross-validation folds per configuration")
+	groupBy := fs.String("group-by", "", "CSV column whose values are kept together in one fold")
+	timeBy := fs.String("time-by", "", "CSV column of academic periods; each fold trains on earlier periods and validates on the next ones")
+	metric := fs.String("metric", "rmse", "metric used to rank configurations: r2, mse, rmse or mae")
+	parallel := fs.Int("parallel", runtime.NumCPU(), "number of configurations trained at the same time")
+	seed := fs.Int64("seed", 1, "seed for the initial weights, fold assignment and random search")
+	schemaPath := fs.String("schema", "", "JSON feature schema declaring the input columns (default: the built-in student schema)")
+	excludeProtected := fs.Bool("exclude-protected", false, "search and refit without the protected columns of the schema")
+	fs.Parse(args)
+	opts.Seed = seed
+
+	if *dataPath == "" || *target == "" {
+		fs.Usage()
+		return fmt.Errorf("-data and -target are required")
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	var space lr.SearchSpace
+	var err error
+	if space.LearningRates, err = parseFloatList(*lrs); err != nil {
+		return err
+	}
+	if space.Epochs, err = parseIntList(*epochGrid); err != nil {
+		return err
+	}
+	if space.Lambdas, err = parseFloatList(*lambdas); err != nil {
+		return err
+	}
+	if space.L1Ratios, err = parseFloatList(*l1Ratios); err != nil {
+		return err
+	}
+	candidates := space.Grid(opts)
+	if *random > 0 {
+		candidates = space.Random(opts, *random, *seed)
+	}
+	for _, c := range candidates {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("invalid search space: %w", err)
+		}
+	}
+
+	schema, err := trainingSchema(*schemaPath, *excludeProtected)
+	if err != nil {
+		return err
+	}
+	if err := checkModelPath(*outPath, schema); err != nil {
+		return err
+	}
+	set, err := loadTrainingCSV(*dataPath, *target, schema)
+	if err != nil {
+		return err
+	}
+	splits, err := buildSplits(set, *folds, *groupBy, *timeBy, *seed)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Searching %d configurations with %d-fold cross-validation on %d rows.\n", len(candidates), *folds, len(set.xs))
+
+	results, err := lr.Search(set.xs, set.ys, candidates, splits, *metric, *parallel)
+	if err != nil {
+		return err
+	}
+
+	report := searchReport{
+		CreatedAt:  time.Now().UTC(),
+		Metric:     *metric,
+		Folds:      *folds,
+		GroupBy:    *groupBy,
+		TimeBy:     *timeBy,
+		Seed:       *seed,
+		Candidates: len(candidates),
+		Results:    results,
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode search report: %w", err)
+	}
+	if err := os.WriteFile(*reportPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write search report: %w", err)
+	}
+
+	fmt.Printf("  %-4s %10s %8s %10s %10s %10s\n", "Rank", "LR", "Epochs", "Lambda", "L1 ratio", *metric)
+	for i, r := range results[:min(10, len(results))] {
+		o := r.Options
+		if r.Error != "" {
+			fmt.Printf("  %-4d %10g %8d %10g %10g %10s\n", i+1, o.LearningRate, o.Epochs, o.Regularization.Lambda, o.Regularization.L1Ratio, "failed: "+r.Error)
+			continue
+		}
+		fmt.Printf("  %-4d %10g %8d %10g %10g %10.6f\n", i+1, o.LearningRate, o.Epochs, o.Regularization.Lambda, o.Regularization.L1Ratio, r.Score)
+	}
+	fmt.Printf("Search report written to %s\n", *reportPath)
+
+	best := results[0]
+	if best.Error != "" {
+		return fmt.Errorf("every configuration failed; best error: %s", best.Error)
+	}
+	start := time.Now()
+	model, _, err := trainConfig{solver: "gd", opts: best.Options, schema: schema}.fit(context.Background(), set.xs, set.ys, nil)
+	if err != nil {
+		return fmt.Errorf("refitting the best configuration failed: %w", err)
+	}
+	elapsed := time.Since(start)
+	r2, mse, rmse := model.Evaluate(set.xs, set.ys)
+	summary := lr.TrainingSummary{TrainingTime: elapsed, R2: r2, MSE: mse, RMSE: rmse}
+	if err := writeModelFile(model, summary, *outPath); err != nil {
+		return err
+	}
+	fmt.Printf("Best configuration refit on all rows (R²=%.6f, RMSE=%.6f). Model written to %s\n", r2, rmse, *outPath)
+	return nil
+}
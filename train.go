@@ -0,0 +1,687 @@
+package main
+
+import (
+	"backend/lr"
+	"backend/utils"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type trainingSet struct {
+	header  []string
+	records [][]string
+	xs      [][]float64
+	ys      []float64
+}
+
+// loadTrainingCSV reads every row of filename and extracts its features with
+// schema.
+func loadTrainingCSV(filename, target string, schema *utils.FeatureSchema) (*trainingSet, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	set := &trainingSet{header: header}
+	binder := schema.Bind(header)
+	targetIdx := set.columnIndex(target)
+	if targetIdx < 0 {
+		return nil, fmt.Errorf("target column %q not found in CSV header", target)
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		x, y, err := parseTrainingRecord(binder, header, record, targetIdx)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		set.records = append(set.records, record)
+		set.xs = append(set.xs, x)
+		set.ys = append(set.ys, y)
+	}
+	if len(set.xs) == 0 {
+		return nil, fmt.Errorf("no training rows found in %s", filename)
+	}
+	return set, nil
+}
+
+func parseTrainingRecord(binder *utils.Binder, header, record []string, targetIdx int) ([]float64, float64, error) {
+	if targetIdx >= len(record) {
+		return nil, 0, fmt.Errorf("missing target column %q", utils.NormalizeCSVHeader(header[targetIdx]))
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(record[targetIdx]), 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid target value %q", record[targetIdx])
+	}
+	features, err := binder.FromRecord(record, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	return features, y, nil
+}
+
+func (t *trainingSet) columnIndex(name string) int {
+	return headerIndex(t.header, name)
+}
+
+func headerIndex(header []string, name string) int {
+	for i, column := range header {
+		if utils.NormalizeCSVHeader(column) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// csvRows reads training rows from a CSV file one chunk at a time for
+// lr.FitStream, so the file never has to fit in memory.
+type csvRows struct {
+	file      *os.File
+	reader    *csv.Reader
+	header    []string
+	targetIdx int
+	binder    *utils.Binder
+	line      int
+}
+
+func csvRowSource(filename, target string, schema *utils.FeatureSchema) lr.RowSource {
+	return func() (lr.RowReader, error) {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		reader := csv.NewReader(bufio.NewReader(file))
+		reader.FieldsPerRecord = -1
+		reader.ReuseRecord = true
+		header, err := reader.Read()
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		header = append([]string(nil), header...)
+		targetIdx := headerIndex(header, target)
+		if targetIdx < 0 {
+			file.Close()
+			return nil, fmt.Errorf("target column %q not found in CSV header", target)
+		}
+		return &csvRows{file: file, reader: reader, header: header, targetIdx: targetIdx, binder: schema.Bind(header), line: 1}, nil
+	}
+}
+
+func (r *csvRows) Next(xs [][]float64, ys []float64) (int, error) {
+	for n := range xs {
+		record, err := r.reader.Read()
+		if err == io.EOF {
+			return n, io.EOF
+		}
+		r.line++
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		features, y, err := parseTrainingRecord(r.binder, r.header, record, r.targetIdx)
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		copy(xs[n], features)
+		ys[n] = y
+	}
+	return len(xs), nil
+}
+
+func (r *csvRows) Close() error {
+	return r.file.Close()
+}
+
+func (t *trainingSet) column(name string) ([]string, error) {
+	idx := t.columnIndex(name)
+	if idx < 0 {
+		return nil, fmt.Errorf("column %q not found in CSV header", name)
+	}
+	values := make([]string, len(t.records))
+	for i, record := range t.records {
+		if idx < len(record) {
+			values[i] = strings.TrimSpace(record[idx])
+		}
+	}
+	return values, nil
+}
+
+type trainConfig struct {
+	solver string
+	opts   lr.FitOptions
+	schema *utils.FeatureSchema
+}
+
+func (c trainConfig) fit(ctx context.Context, xs [][]float64, ys, sampleWeights []float64) (*lr.LinearRegression, *lr.SolverReport, error) {
+	model := lr.New(c.schema.Count())
+	model.FeatureNames = c.schema.Names()
+	model.FeatureSchema = c.schema
+	if c.solver == "exact" {
+		report, err := model.FitExactWeighted(xs, ys, sampleWeights)
+		return model, report, err
+	}
+	opts := c.opts
+	opts.SampleWeights = sampleWeights
+	err := model.FitContext(ctx, xs, ys, opts)
+	return model, nil, err
+}
+
+func (c trainConfig) withoutObservers() trainConfig {
+	c.opts.Observers = nil
+	return c
+}
+
+func (c trainConfig) trainer(ctx context.Context) lr.WeightedTrainer {
+	return func(xs [][]float64, ys, sampleWeights []float64) (*lr.LinearRegression, error) {
+		model, _, err := c.fit(ctx, xs, ys, sampleWeights)
+		return model, err
+	}
+}
+
+// sampleWeights multiplies the numeric weightColumn with inverse-frequency
+// weights for balanceBy, under which every distinct value of that column
+// carries the same total weight. Both are optional; nil means unweighted.
+func (t *trainingSet) sampleWeights(weightColumn, balanceBy string) ([]float64, error) {
+	if weightColumn == "" && balanceBy == "" {
+		return nil, nil
+	}
+	weights := make([]float64, len(t.xs))
+	for i := range weights {
+		weights[i] = 1
+	}
+	if weightColumn != "" {
+		values, err := t.column(weightColumn)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			w, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid weight %q in column %s", i+2, v, weightColumn)
+			}
+			weights[i] = w
+		}
+	}
+	if balanceBy != "" {
+		groups, err := t.column(balanceBy)
+		if err != nil {
+			return nil, err
+		}
+		counts := make(map[string]int)
+		for _, g := range groups {
+			counts[g]++
+		}
+		for i, g := range groups {
+			weights[i] *= float64(len(groups)) / float64(len(counts)*counts[g])
+		}
+	}
+	return weights, nil
+}
+
+func registerFitFlags(fs *flag.FlagSet, opts *lr.FitOptions) {
+	fs.IntVar(&opts.Epochs, "epochs", opts.Epochs, "number of training epochs")
+	fs.Float64Var(&opts.LearningRate, "lr", opts.LearningRate, "initial learning rate")
+	fs.IntVar(&opts.Workers, "workers", opts.Workers, "number of goroutines computing gradients")
+	fs.Float64Var(&opts.Regularization.Lambda, "lambda", 0, "regularization strength applied to the standardized weights (gd solver only)")
+	fs.Float64Var(&opts.Regularization.L1Ratio, "l1-ratio", 0, "share of the penalty that is L1: 0 is ridge, 1 is lasso, in between is elastic-net")
+	fs.IntVar(&opts.Patience, "patience", opts.Patience, "loss checks without improvement before the learning rate decays")
+	fs.Float64Var(&opts.DecayFactor, "decay", opts.DecayFactor, "factor applied to the learning rate when patience runs out")
+	fs.Float64Var(&opts.GradientClip, "clip", opts.GradientClip, "clip each gradient component to ±clip (0 disables clipping)")
+	fs.Float64Var(&opts.LossThreshold, "loss-threshold", opts.LossThreshold, "stop once the standardized training loss falls below this value")
+	fs.Float64Var(&opts.MinLearningRate, "min-lr", opts.MinLearningRate, "stop once the decayed learning rate falls below this value")
+	fs.IntVar(&opts.EarlyStopping.Patience, "early-stopping", opts.EarlyStopping.Patience, "stop after this many epochs without validation loss improvement and keep the best weights (0 disables)")
+	fs.Float64Var(&opts.EarlyStopping.MinDelta, "min-delta", opts.EarlyStopping.MinDelta, "smallest validation loss decrease that counts as an improvement")
+	fs.Float64Var(&opts.EarlyStopping.ValidationFraction, "validation-fraction", opts.EarlyStopping.ValidationFraction, "share of training rows held out to monitor early stopping (0 uses 0.1)")
+	fs.BoolVar(&opts.FixedEpochs, "fixed-epochs", opts.FixedEpochs, "run every epoch instead of stopping at -loss-threshold or -min-lr")
+	fs.DurationVar(&opts.TimeBudget, "time-budget", opts.TimeBudget, "stop gradient descent after this much wall-clock time and keep the model reached so far (0 means unlimited)")
+	fs.IntVar(&opts.BatchSize, "batch-size", opts.BatchSize, "rows per shuffled mini-batch (0 uses every row for each step)")
+	fs.StringVar(&opts.Optimizer.Name, "optimizer", opts.Optimizer.Name, "update rule: sgd, momentum, nesterov, rmsprop or adam (rmsprop and adam do not support -l1-ratio)")
+	fs.Float64Var(&opts.Optimizer.Momentum, "momentum", opts.Optimizer.Momentum, "momentum coefficient for the momentum and nesterov optimizers (0 uses 0.9)")
+	fs.Float64Var(&opts.Optimizer.Beta1, "beta1", opts.Optimizer.Beta1, "Adam first-moment decay (0 uses 0.9)")
+	fs.Float64Var(&opts.Optimizer.Beta2, "beta2", opts.Optimizer.Beta2, "Adam second-moment decay (0 uses 0.999)")
+	fs.Float64Var(&opts.Optimizer.Decay, "rms-decay", opts.Optimizer.Decay, "RMSProp squared-gradient decay (0 uses 0.9)")
+	fs.StringVar(&opts.Schedule.Name, "schedule", opts.Schedule.Name, "learning rate schedule: constant, step, exponential or cosine")
+	fs.IntVar(&opts.Schedule.StepSize, "step-size", opts.Schedule.StepSize, "epochs between decays for the step schedule (0 uses 1000)")
+	fs.Float64Var(&opts.Schedule.Gamma, "gamma", opts.Schedule.Gamma, "decay factor for the step (0 uses 0.5) and exponential (0 uses 0.999) schedules")
+}
+
+// buildSplits returns shuffled k-fold splits, grouped ones when groupBy is
+// set, or forward-chaining ones over the periods of timeBy.
+func buildSplits(set *trainingSet, folds int, groupBy, timeBy string, seed int64) ([]lr.Split, error) {
+	switch {
+	case groupBy != "" && timeBy != "":
+		return nil, fmt.Errorf("-group-by and -time-by cannot be combined")
+	case timeBy != "":
+		periods, err := set.column(timeBy)
+		if err != nil {
+			return nil, err
+		}
+		return lr.ForwardChainingSplit(periods, folds)
+	case groupBy != "":
+		groups, err := set.column(groupBy)
+		if err != nil {
+			return nil, err
+		}
+		return lr.GroupKFold(groups, folds, seed)
+	}
+	return lr.KFold(len(set.xs), folds, seed)
+}
+
+func printCrossValidation(title string, result *lr.CrossValidationResult) {
+	fmt.Println(title)
+	fmt.Printf("  %-6s %8s %8s %10s %10s %10s %10s\n", "Fold", "Train", "Test", "R²", "MSE", "RMSE", "MAE")
+	for _, f := range result.Folds {
+		fmt.Printf("  %-6d %8d %8d %10.6f %10.6f %10.6f %10.6f\n", f.Fold, f.TrainSize, f.TestSize, f.Metrics.R2, f.Metrics.MSE, f.Metrics.RMSE, f.Metrics.MAE)
+	}
+	if len(result.Folds) > 1 {
+		m, sd := result.Mean, result.Std
+		fmt.Printf("  %-24s %10.6f %10.6f %10.6f %10.6f\n", "Mean", m.R2, m.MSE, m.RMSE, m.MAE)
+		fmt.Printf("  %-24s %10.6f %10.6f %10.6f %10.6f\n", "Std", sd.R2, sd.MSE, sd.RMSE, sd.MAE)
+	}
+}
+
+// inferenceOutput selects how runTrain reports coefficient inference: as a
+// table on stdout, as a JSON file, or both.
+type inferenceOutput struct {
+	table bool
+	path  string
+	level float64
+}
+
+func (o inferenceOutput) report(model *lr.LinearRegression) error {
+	if !o.table && o.path == "" {
+		return nil
+	}
+	report, err := model.CoefficientInference(o.level)
+	if err != nil {
+		return fmt.Errorf("coefficient inference failed: %w", err)
+	}
+	if o.table {
+		printCoefficientReport(report)
+		if model.Params.Regularization.Lambda > 0 {
+			fmt.Println("  Note: the model is regularized, so these OLS statistics are only indicative.")
+		}
+	}
+	if o.path != "" {
+		if err := writeJSONReport(o.path, report); err != nil {
+			return err
+		}
+		fmt.Printf("Coefficient inference written to %s\n", o.path)
+	}
+	return nil
+}
+
+// significanceCode follows the usual R convention.
+func significanceCode(p float64) string {
+	switch {
+	case p < 0.001:
+		return "***"
+	case p < 0.01:
+		return "**"
+	case p < 0.05:
+		return "*"
+	case p < 0.1:
+		return "."
+	}
+	return ""
+}
+
+func printCoefficientReport(report *lr.CoefficientReport) {
+	fmt.Printf("Coefficients (residual std. error %.6f on %d degrees of freedom, %.0f%% intervals):\n", report.ResidualStdError, report.DegreesOfFreedom, report.Level*100)
+	fmt.Printf("  %-50s %12s %12s %9s %10s %12s %12s %8s\n", "Feature", "Estimate", "Std. error", "t", "p", "Lower", "Upper", "VIF")
+	rows := append([]lr.CoefficientStats{report.Intercept}, report.Coefficients...)
+	for _, c := range rows {
+		name := c.Feature
+		if runes := []rune(name); len(runes) > 50 {
+			name = string(runes[:47]) + "..."
+		}
+		if c.Aliased {
+			fmt.Printf("  %-50s %12.6f %12s   (aliased: constant or linearly dependent)\n", name, c.Estimate, "-")
+			continue
+		}
+		vif := ""
+		if c.VIF > 0 {
+			vif = fmt.Sprintf("%8.2f", c.VIF)
+		}
+		fmt.Printf("  %-50s %12.6f %12.6f %9.3f %10.4g %12.6f %12.6f %8s %s\n", name, c.Estimate, c.StdError, c.TStat, c.PValue, c.Lower, c.Upper, vif, significanceCode(c.PValue))
+	}
+	fmt.Println("  Significance: *** p<0.001, ** p<0.01, * p<0.05, . p<0.1")
+}
+
+func writeJSONReport(filename string, report any) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// trainingSchema loads the schema at path, or the default one when path is
+// empty, optionally without its protected columns.
+func trainingSchema(path string, excludeProtected bool) (*utils.FeatureSchema, error) {
+	schema := utils.DefaultSchema()
+	if path != "" {
+		var err error
+		if schema, err = utils.LoadSchema(path); err != nil {
+			return nil, err
+		}
+	}
+	if excludeProtected {
+		schema = schema.WithoutProtected()
+	}
+	if schema.Count() == 0 {
+		return nil, fmt.Errorf("the feature schema produces no features")
+	}
+	return schema, nil
+}
+
+func runTrain(args []string) error {
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	dataPath := fs.String("data", "", "CSV file with one column per schema column plus the target column")
+	target := fs.String("target", "", "name of the CSV column to predict")
+	outPath := fs.String("out", "model.json", "where to write the trained model; a .json extension selects the versioned JSON format, anything else the legacy text format without normalization or uncertainty statistics")
+	opts := lr.DefaultFitOptions()
+	opts.Workers = runtime.NumCPU()
+	registerFitFlags(fs, &opts)
+	solver := fs.String("solver", "gd", "training algorithm: gd (gradient descent) or exact (least squares via QR)")
+	folds := fs.Int("cv", 0, "report k-fold cross-validation metrics before the final fit (0 disables)")
+	groupBy := fs.String("group-by", "", "CSV column whose values are kept together in one fold, e.g. PERIODO_ACADEMICO_ANTERIOR")
+	timeBy := fs.String("time-by", "", "CSV column of academic periods, e.g. PERIODO_ACADEMICO_ANTERIOR; each of the -cv folds trains on earlier periods and validates on the next ones")
+	holdout := fs.Float64("holdout", 0, "report metrics on this fraction of rows held out before the final fit (0 disables)")
+	seed := fs.Int64("seed", 1, "seed for the initial weights and for shuffling rows into folds and holdout splits")
+	progress := fs.Int("progress", 100, "log gradient descent progress of the final fit every n epochs (0 disables)")
+	weightColumn := fs.String("weight-column", "", "numeric CSV column with a sample weight for every row")
+	balanceBy := fs.String("balance-by", "", "weight rows so every value of this CSV column (e.g. PROGRAMA) carries the same total weight")
+	stream := fs.Bool("stream", false, "read the CSV in mini-batches on every epoch instead of loading it into memory (gd solver only; rows are used in file order)")
+	var inference inferenceOutput
+	fs.BoolVar(&inference.table, "inference", false, "print standard errors, t-statistics, p-values, confidence intervals and VIFs of the coefficients (exact solver only)")
+	fs.StringVar(&inference.path, "inference-out", "", "also write the coefficient inference as JSON to this file (exact solver only)")
+	fs.Float64Var(&inference.level, "inference-level", 0.95, "confidence level of the coefficient intervals")
+	schemaPath := fs.String("schema", "", "JSON feature schema declaring the input columns (default: the built-in student schema, see the schema subcommand)")
+	excludeProtected := fs.Bool("exclude-protected", false, "train without the protected columns of the schema (GENERO and DISCAPACIDAD by default); the saved schema marks them excluded, so the server ignores them")
+	fs.Parse(args)
+	opts.Seed = seed
+
+	if *dataPath == "" || *target == "" {
+		fs.Usage()
+		return fmt.Errorf("-data and -target are required")
+	}
+	if *solver != "gd" && *solver != "exact" {
+		return fmt.Errorf("unknown solver %q", *solver)
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if !(inference.level > 0 && inference.level < 1) {
+		return fmt.Errorf("-inference-level must be between 0 and 1, got %v", inference.level)
+	}
+	if *solver == "exact" && opts.Regularization.Lambda > 0 {
+		return fmt.Errorf("-lambda is only supported by the gd solver")
+	}
+	// The one-hot blocks make the design rank-deficient, which gradient
+	// descent fits cannot report on; refuse before the model file is replaced.
+	if (inference.table || inference.path != "") && *solver != "exact" {
+		return fmt.Errorf("-inference and -inference-out need -solver exact: %w", lr.ErrRankDeficient)
+	}
+	if (*groupBy != "" || *timeBy != "") && *folds == 0 {
+		return fmt.Errorf("-group-by and -time-by require -cv")
+	}
+	if *stream && (*solver != "gd" || *folds > 0 || *holdout > 0 || *weightColumn != "" || *balanceBy != "") {
+		return fmt.Errorf("-stream only supports the gd solver without -cv, -holdout or sample weights")
+	}
+	schema, err := trainingSchema(*schemaPath, *excludeProtected)
+	if err != nil {
+		return err
+	}
+	if err := checkModelPath(*outPath, schema); err != nil {
+		return err
+	}
+	cfg := trainConfig{solver: *solver, opts: opts, schema: schema}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *progress > 0 {
+		cfg.opts.Observers = []lr.Observer{lr.SlogObserver(slog.Default(), *progress)}
+	}
+	if *stream {
+		return trainStream(ctx, cfg, csvRowSource(*dataPath, *target, cfg.schema), *outPath, inference)
+	}
+
+	set, err := loadTrainingCSV(*dataPath, *target, cfg.schema)
+	if err != nil {
+		return err
+	}
+	xs, ys := set.xs, set.ys
+	fmt.Printf("Loaded %d training rows with %d features.\n", len(xs), len(xs[0]))
+	weights, err := set.sampleWeights(*weightColumn, *balanceBy)
+	if err != nil {
+		return err
+	}
+
+	if *holdout > 0 {
+		split, err := lr.HoldoutSplit(len(xs), *holdout, *seed)
+		if err != nil {
+			return err
+		}
+		result, err := lr.CrossValidateWeighted(xs, ys, weights, []lr.Split{split}, cfg.withoutObservers().trainer(ctx))
+		if err != nil {
+			return fmt.Errorf("holdout evaluation failed: %w", err)
+		}
+		printCrossValidation(fmt.Sprintf("Holdout evaluation (%.0f%% of rows held out):", *holdout*100), result)
+	}
+	if *folds > 0 {
+		splits, err := buildSplits(set, *folds, *groupBy, *timeBy, *seed)
+		if err != nil {
+			return err
+		}
+		title := fmt.Sprintf("%d-fold cross-validation:", *folds)
+		if *groupBy != "" {
+			title = fmt.Sprintf("%d-fold cross-validation grouped by %s:", *folds, *groupBy)
+		}
+		if *timeBy != "" {
+			title = fmt.Sprintf("%d-fold forward-chaining validation over %s:", *folds, *timeBy)
+		}
+		result, err := lr.CrossValidateWeighted(xs, ys, weights, splits, cfg.withoutObservers().trainer(ctx))
+		if err != nil {
+			return fmt.Errorf("cross-validation failed: %w", err)
+		}
+		printCrossValidation(title, result)
+	}
+
+	start := time.Now()
+	model, report, err := cfg.fit(ctx, xs, ys, weights)
+	stop()
+	var interrupted *lr.InterruptedError
+	if errors.As(err, &interrupted) {
+		fmt.Printf("Training stopped early (%v); keeping the partial model.\n", interrupted)
+	} else if err != nil {
+		return fmt.Errorf("training failed: %w", err)
+	}
+	elapsed := time.Since(start)
+	if report != nil && report.RankDeficient() {
+		names := cfg.schema.Names()
+		fmt.Printf("Design matrix is rank deficient (rank %d of %d); these features were fixed at 0:\n", report.Rank, report.NumFeatures)
+		for _, j := range report.DependentFeatures {
+			fmt.Printf("  W%d %s\n", j, names[j])
+		}
+	}
+
+	r2, mse, rmse := model.EvaluateWeighted(xs, ys, weights)
+	summary := lr.TrainingSummary{
+		TrainingTime: elapsed,
+		R2:           r2,
+		MSE:          mse,
+		RMSE:         rmse,
+	}
+	if err := writeModelFile(model, summary, *outPath); err != nil {
+		return err
+	}
+	if es := model.EarlyStopping; es != nil {
+		fmt.Printf("Early stopping kept the weights from epoch %d (validation loss %.6f).\n", es.BestEpoch, es.BestValidationLoss)
+	}
+	fmt.Printf("Training finished in %v (R²=%.6f, RMSE=%.6f). Model written to %s\n", elapsed, r2, rmse, *outPath)
+	return inference.report(model)
+}
+
+func trainStream(ctx context.Context, cfg trainConfig, source lr.RowSource, outPath string, inference inferenceOutput) error {
+	model := lr.New(cfg.schema.Count())
+	model.FeatureNames = cfg.schema.Names()
+	model.FeatureSchema = cfg.schema
+	start := time.Now()
+	err := model.FitStream(ctx, source, cfg.opts)
+	var interrupted *lr.InterruptedError
+	if errors.As(err, &interrupted) {
+		fmt.Printf("Training stopped early (%v); keeping the partial model.\n", interrupted)
+	} else if err != nil {
+		return fmt.Errorf("training failed: %w", err)
+	}
+	elapsed := time.Since(start)
+	// An interrupted fit is still evaluated and written, so the evaluation
+	// pass must not inherit the cancellation.
+	r2, mse, rmse, err := model.EvaluateStream(context.WithoutCancel(ctx), source)
+	if err != nil {
+		return fmt.Errorf("evaluation failed: %w", err)
+	}
+	summary := lr.TrainingSummary{TrainingTime: elapsed, R2: r2, MSE: mse, RMSE: rmse}
+	if err := writeModelFile(model, summary, outPath); err != nil {
+		return err
+	}
+	fmt.Printf("Streaming training finished in %v (R²=%.6f, RMSE=%.6f). Model written to %s\n", elapsed, r2, rmse, outPath)
+	return inference.report(model)
+}
+
+func isJSONModelPath(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".json")
+}
+
+// checkModelPath rejects writing a model with a custom schema to the legacy
+// text format. That format does not store the schema, and loadModel would
+// serve the model with the default one (see legacySchema), silently
+// mismatching the weights if only the column order differs.
+func checkModelPath(filename string, schema *utils.FeatureSchema) error {
+	if isJSONModelPath(filename) {
+		return nil
+	}
+	defaults := utils.DefaultSchema()
+	if reflect.DeepEqual(schema, defaults) || reflect.DeepEqual(schema, defaults.WithoutProtected()) {
+		return nil
+	}
+	return fmt.Errorf("%s is a legacy text model, which cannot store a custom feature schema; use a .json file", filename)
+}
+
+func writeModelFile(model *lr.LinearRegression, summary lr.TrainingSummary, filename string) error {
+	if err := checkModelPath(filename, model.FeatureSchema); err != nil {
+		return err
+	}
+	var data []byte
+	if isJSONModelPath(filename) {
+		var err error
+		data, err = model.ExportModel(summary)
+		if err != nil {
+			return fmt.Errorf("failed to encode model: %w", err)
+		}
+	} else {
+		data = []byte(model.ExportModelToString(summary))
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write model: %w", err)
+	}
+	return nil
+}
+
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	modelPath := fs.String("model", "", "print the feature schema saved with this model instead of the built-in one")
+	outPath := fs.String("out", "", "write the schema to this file instead of stdout")
+	fs.Parse(args)
+
+	schema := utils.DefaultSchema()
+	if *modelPath != "" {
+		model, err := loadModel(*modelPath)
+		if err != nil {
+			return fmt.Errorf("failed to load model: %w", err)
+		}
+		schema = model.FeatureSchema
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+	if *outPath == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(*outPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	fmt.Printf("Feature schema with %d columns and %d features written to %s\n", len(schema.Columns), schema.Count(), *outPath)
+	return nil
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	inPath := fs.String("in", "values.txt", "model file in the legacy text format")
+	outPath := fs.String("out", "model.json", "where to write the versioned JSON model")
+	fs.Parse(args)
+
+	data, err := os.ReadFile(*inPath)
+	if err != nil {
+		return err
+	}
+	doc, err := lr.ReadModelDocument(data)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", *inPath, err)
+	}
+	if doc.SchemaVersion == 0 {
+		doc.SchemaVersion = lr.ModelSchemaVersion
+		doc.CreatedAt = time.Now().UTC()
+	}
+	if doc.FeatureSchema == nil {
+		for _, schema := range []*utils.FeatureSchema{utils.DefaultSchema(), utils.DefaultSchema().WithoutProtected()} {
+			if slices.Equal(schema.Names(), doc.Features) {
+				doc.FeatureSchema = schema
+				break
+			}
+		}
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode model: %w", err)
+	}
+	if err := os.WriteFile(*outPath, out, 0644); err != nil {
+		return fmt.Errorf("failed to write model: %w", err)
+	}
+	fmt.Printf("Migrated %d weights from %s to %s\n", len(doc.Features), *inPath, *outPath)
+	return nil
+}
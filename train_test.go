@@ -0,0 +1,56 @@
+package main
+
+import (
+	"backend/lr"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTrainingFiles writes a two-column schema and a CSV that fits it.
+func writeTrainingFiles(t *testing.T) (dir, schemaPath, dataPath string) {
+	t.Helper()
+	dir = t.TempDir()
+	schemaPath = filepath.Join(dir, "schema.json")
+	schema := `{"version": 1, "columns": [{"name": "EDAD", "type": "numeric"}, {"name": "CREDITOS", "type": "numeric"}]}`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dataPath = filepath.Join(dir, "data.csv")
+	data := "EDAD,CREDITOS,PROMEDIO\n18,20,11.5\n19,22,12.1\n21,18,10.9\n20,24,13.2\n23,16,10.1\n22,21,12.0\n"
+	if err := os.WriteFile(dataPath, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, schemaPath, dataPath
+}
+
+func TestTrainRejectsInferenceWithoutExactSolver(t *testing.T) {
+	dir, schemaPath, dataPath := writeTrainingFiles(t)
+	outPath := filepath.Join(dir, "model.json")
+	inferencePath := filepath.Join(dir, "inference.json")
+	err := runTrain([]string{"-data", dataPath, "-target", "PROMEDIO", "-schema", schemaPath, "-out", outPath, "-epochs", "10", "-inference", "-inference-out", inferencePath})
+	if !errors.Is(err, lr.ErrRankDeficient) {
+		t.Fatalf("got %v, want ErrRankDeficient", err)
+	}
+	for _, path := range []string{outPath, inferencePath} {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s was written before the inference flags were rejected", filepath.Base(path))
+		}
+	}
+}
+
+func TestTrainWritesInferenceWithExactSolver(t *testing.T) {
+	dir, schemaPath, dataPath := writeTrainingFiles(t)
+	outPath := filepath.Join(dir, "model.json")
+	inferencePath := filepath.Join(dir, "inference.json")
+	err := runTrain([]string{"-data", dataPath, "-target", "PROMEDIO", "-schema", schemaPath, "-out", outPath, "-solver", "exact", "-inference-out", inferencePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{outPath, inferencePath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+}
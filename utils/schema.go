@@ -0,0 +1,395 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the feature schema format written by this
+// build.
+const SchemaVersion = 1
+
+type ColumnType string
+
+const (
+	// Numeric columns are parsed as floats.
+	Numeric ColumnType = "numeric"
+	// Date columns become days since 1970-01-01.
+	Date ColumnType = "date"
+	// Period columns are academic periods such as "2024-2" or "20242",
+	// parsed as a number after removing dashes.
+	Period ColumnType = "period"
+	// Boolean columns become 1 when the value equals True and 0 otherwise.
+	Boolean ColumnType = "boolean"
+	// Category columns become one 0/1 feature per vocabulary value.
+	Category ColumnType = "category"
+)
+
+const defaultDateFormat = "2006-01-02"
+
+// Column declares one input column, how it is turned into features and the
+// rules strict validation applies to it. Values are compared after trimming
+// surrounding whitespace, so " Si" and "Si" are the same category.
+type Column struct {
+	Name string     `json:"name"`
+	Type ColumnType `json:"type"`
+	// Feature names the feature of a single-feature column (default Name);
+	// Prefix is prepended to every category of a Category column.
+	Feature string `json:"feature,omitempty"`
+	Prefix  string `json:"prefix,omitempty"`
+	// Values is the vocabulary of a Category column and the accepted values
+	// of a Boolean column, of which True is encoded as 1.
+	Values []string `json:"values,omitempty"`
+	True   string   `json:"true,omitempty"`
+	// Min and Max bound Numeric values and the year of Period values; Integer
+	// requires whole numbers, and MaxColumn names a Numeric column this one
+	// cannot exceed.
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Integer   bool     `json:"integer,omitempty"`
+	MaxColumn string   `json:"max_column,omitempty"`
+	// Format is the Go time layout of a Date column (default 2006-01-02);
+	// dates must fall in [MinDate, MaxDate) when those are set.
+	Format  string `json:"format,omitempty"`
+	MinDate string `json:"min_date,omitempty"`
+	MaxDate string `json:"max_date,omitempty"`
+	// Protected marks attributes audited for fairness. Excluded columns are
+	// kept in the schema for reference but produce no features and are not
+	// required.
+	Protected bool `json:"protected,omitempty"`
+	Excluded  bool `json:"excluded,omitempty"`
+}
+
+// FeatureSchema declares the input columns in feature order. Both the
+// feature vector and the feature names are generated from it, and it is
+// saved with every trained model.
+type FeatureSchema struct {
+	Version int      `json:"version"`
+	Columns []Column `json:"columns"`
+}
+
+func (c *Column) featureName() string {
+	if c.Feature != "" {
+		return c.Feature
+	}
+	return c.Name
+}
+
+func (c *Column) dateFormat() string {
+	if c.Format != "" {
+		return c.Format
+	}
+	return defaultDateFormat
+}
+
+// LoadSchema reads a JSON feature schema and checks it.
+func LoadSchema(filename string) (*FeatureSchema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var schema FeatureSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid feature schema %s: %w", filename, err)
+	}
+	if err := schema.Check(); err != nil {
+		return nil, fmt.Errorf("invalid feature schema %s: %w", filename, err)
+	}
+	return &schema, nil
+}
+
+// Check reports the first inconsistency in the schema: unknown types,
+// duplicate columns or feature names, empty vocabularies, a boolean true
+// value missing from its values, bad date bounds or a MaxColumn that is not
+// a numeric column.
+func (s *FeatureSchema) Check() error {
+	if s.Version != SchemaVersion {
+		return fmt.Errorf("unsupported schema version %d (expected %d)", s.Version, SchemaVersion)
+	}
+	columns := make(map[string]*Column, len(s.Columns))
+	for i := range s.Columns {
+		c := &s.Columns[i]
+		if strings.TrimSpace(c.Name) == "" {
+			return fmt.Errorf("column %d has no name", i)
+		}
+		if _, dup := columns[c.Name]; dup {
+			return fmt.Errorf("column %s is declared more than once", c.Name)
+		}
+		columns[c.Name] = c
+		switch c.Type {
+		case Numeric, Period:
+		case Date:
+			for _, bound := range []string{c.MinDate, c.MaxDate} {
+				if bound == "" {
+					continue
+				}
+				if _, err := time.Parse(c.dateFormat(), bound); err != nil {
+					return fmt.Errorf("column %s: date bound %q does not match format %s", c.Name, bound, c.dateFormat())
+				}
+			}
+		case Boolean:
+			if c.True == "" {
+				return fmt.Errorf("column %s: boolean columns need a true value", c.Name)
+			}
+			if len(c.Values) > 0 && !slices.ContainsFunc(c.Values, func(v string) bool {
+				return strings.TrimSpace(v) == strings.TrimSpace(c.True)
+			}) {
+				return fmt.Errorf("column %s: true value %q is not one of its values", c.Name, c.True)
+			}
+		case Category:
+			if len(c.Values) == 0 {
+				return fmt.Errorf("column %s: category columns need at least one value", c.Name)
+			}
+			seen := make(map[string]bool, len(c.Values))
+			for _, v := range c.Values {
+				v = strings.TrimSpace(v)
+				if seen[v] {
+					return fmt.Errorf("column %s: category %q is listed more than once", c.Name, v)
+				}
+				seen[v] = true
+			}
+		default:
+			return fmt.Errorf("column %s: unknown type %q", c.Name, c.Type)
+		}
+		if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+			return fmt.Errorf("column %s: min %g is above max %g", c.Name, *c.Min, *c.Max)
+		}
+	}
+	for _, c := range s.Columns {
+		if c.MaxColumn == "" {
+			continue
+		}
+		if other, ok := columns[c.MaxColumn]; !ok || other.Type != Numeric {
+			return fmt.Errorf("column %s: max_column %s is not a numeric column", c.Name, c.MaxColumn)
+		}
+	}
+	names := s.Names()
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return fmt.Errorf("feature %q is generated more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// Names returns the feature names, in vector order.
+func (s *FeatureSchema) Names() []string {
+	var names []string
+	for _, c := range s.Columns {
+		if c.Excluded {
+			continue
+		}
+		if c.Type != Category {
+			names = append(names, c.featureName())
+			continue
+		}
+		for _, v := range c.Values {
+			names = append(names, c.Prefix+strings.TrimSpace(v))
+		}
+	}
+	return names
+}
+
+func (s *FeatureSchema) Count() int {
+	return len(s.Names())
+}
+
+// ColumnNames returns the input columns a record needs, i.e. every column
+// that is not excluded.
+func (s *FeatureSchema) ColumnNames() []string {
+	var names []string
+	for _, c := range s.Columns {
+		if !c.Excluded {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// ProtectedColumns returns the protected columns, excluded or not.
+func (s *FeatureSchema) ProtectedColumns() []Column {
+	var columns []Column
+	for _, c := range s.Columns {
+		if c.Protected {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// FeatureIndex returns the position of a single-feature column's feature in
+// the vector, or -1 for category, excluded or unknown columns.
+func (s *FeatureSchema) FeatureIndex(column string) int {
+	for _, c := range s.Columns {
+		if c.Name == column && c.Type != Category && !c.Excluded {
+			return slices.Index(s.Names(), c.featureName())
+		}
+	}
+	return -1
+}
+
+// WithoutProtected returns a copy of the schema whose protected columns are
+// excluded.
+func (s *FeatureSchema) WithoutProtected() *FeatureSchema {
+	out := &FeatureSchema{Version: s.Version, Columns: slices.Clone(s.Columns)}
+	for i := range out.Columns {
+		if out.Columns[i].Protected {
+			out.Columns[i].Excluded = true
+		}
+	}
+	return out
+}
+
+// FeatureGroup maps a one-hot feature name such as "Programa_AGRONOMIA" to
+// its source column and category ("PROGRAMA", "AGRONOMIA").
+func (s *FeatureSchema) FeatureGroup(name string) (column, category string, ok bool) {
+	for _, c := range s.Columns {
+		if c.Type != Category || c.Excluded {
+			continue
+		}
+		for _, v := range c.Values {
+			if name == c.Prefix+strings.TrimSpace(v) {
+				return c.Name, strings.TrimSpace(v), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Features builds the feature vector from the value of every column, as
+// returned by value. Like the original extractor it never fails: values that
+// do not parse become 0 and unknown categories leave every dummy at 0. Use
+// Validate first to reject them instead.
+func (s *FeatureSchema) Features(value func(column string) string) []float64 {
+	features := make([]float64, 0, s.Count())
+	for i := range s.Columns {
+		c := &s.Columns[i]
+		if c.Excluded {
+			continue
+		}
+		v := strings.TrimSpace(value(c.Name))
+		switch c.Type {
+		case Numeric:
+			f, _ := strconv.ParseFloat(v, 64)
+			features = append(features, f)
+		case Date:
+			days, err := parseDays(v, c.dateFormat())
+			if err != nil {
+				days = 0
+			}
+			features = append(features, days)
+		case Period:
+			f, _ := strconv.ParseFloat(normalizePeriodo(v), 64)
+			features = append(features, f)
+		case Boolean:
+			if v == strings.TrimSpace(c.True) {
+				features = append(features, 1)
+			} else {
+				features = append(features, 0)
+			}
+		case Category:
+			for _, category := range c.Values {
+				if v == strings.TrimSpace(category) {
+					features = append(features, 1)
+				} else {
+					features = append(features, 0)
+				}
+			}
+		}
+	}
+	return features
+}
+
+// ParseJSON extracts the features of a JSON object whose keys are column
+// names, matched case-insensitively like encoding/json matches struct fields.
+// Values of the schema's columns must be strings or numbers; other keys are
+// ignored, and two keys naming the same column are rejected. With strict,
+// records failing Validate are rejected with a *ValidationError.
+func (s *FeatureSchema) ParseJSON(jsonData []byte, strict bool) ([]float64, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool, len(s.Columns))
+	for _, c := range s.Columns {
+		if !c.Excluded {
+			columns[strings.ToLower(c.Name)] = true
+		}
+	}
+	keys := make(map[string]string, len(columns))
+	values := make(map[string]string, len(columns))
+	for key, msg := range raw {
+		column := strings.ToLower(key)
+		if !columns[column] {
+			continue
+		}
+		if other, dup := keys[column]; dup {
+			first, second := min(key, other), max(key, other)
+			return nil, fmt.Errorf("fields %s and %s name the same column", first, second)
+		}
+		keys[column] = key
+		var str string
+		if err := json.Unmarshal(msg, &str); err == nil {
+			values[column] = str
+			continue
+		}
+		var num json.Number
+		if err := json.Unmarshal(msg, &num); err != nil {
+			return nil, fmt.Errorf("field %s must be a string or a number", key)
+		}
+		values[column] = num.String()
+	}
+	return s.extract(func(column string) string { return values[strings.ToLower(column)] }, strict)
+}
+
+// Binder extracts features from CSV records that share one header; the
+// header's column positions are looked up once, in Bind.
+type Binder struct {
+	schema *FeatureSchema
+	index  map[string]int
+}
+
+// Bind matches the schema's columns to header by name.
+func (s *FeatureSchema) Bind(header []string) *Binder {
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[NormalizeCSVHeader(column)] = i
+	}
+	return &Binder{schema: s, index: index}
+}
+
+// FromRecord extracts the features of a CSV record laid out like the bound
+// header.
+func (b *Binder) FromRecord(record []string, strict bool) ([]float64, error) {
+	return b.schema.extract(func(column string) string {
+		if i, ok := b.index[column]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}, strict)
+}
+
+func (s *FeatureSchema) extract(value func(column string) string, strict bool) ([]float64, error) {
+	if strict {
+		if err := s.Validate(value); err != nil {
+			return nil, err
+		}
+	}
+	return s.Features(value), nil
+}
+
+func parseDays(value, format string) (float64, error) {
+	date, err := time.Parse(format, value)
+	if err != nil {
+		return 0, fmt.Errorf("formato de fecha inválido: %v", err)
+	}
+	epoch := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	return date.Sub(epoch).Hours() / 24, nil
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckBooleanTrueValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		column  Column
+		wantErr string
+	}{
+		{"true among values", Column{Name: "BECA", Type: Boolean, True: "Y", Values: []string{"Y", "N"}}, ""},
+		{"values with spaces", Column{Name: "BECA", Type: Boolean, True: "Si", Values: []string{" Si", "No "}}, ""},
+		{"no values", Column{Name: "BECA", Type: Boolean, True: "Si"}, ""},
+		{"true not among values", Column{Name: "BECA", Type: Boolean, True: "yes", Values: []string{"Y", "N"}}, `true value "yes" is not one of its values`},
+		{"no true value", Column{Name: "BECA", Type: Boolean, Values: []string{"Y", "N"}}, "need a true value"},
+	}
+	for _, tt := range tests {
+		schema := FeatureSchema{Version: SchemaVersion, Columns: []Column{tt.column}}
+		err := schema.Check()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDefaultSchemaPassesCheck(t *testing.T) {
+	if err := DefaultSchema().Check(); err != nil {
+		t.Fatal(err)
+	}
+}
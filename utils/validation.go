@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FieldError struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError collects every problem found in a record so callers can
+// report them all at once instead of one per round trip.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fmt.Sprintf("%s=%q: %s", fe.Field, fe.Value, fe.Reason)
+	}
+	return "invalid student data: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(field, value, reason string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Value: value, Reason: reason})
+}
+
+func (e *ValidationError) requireNumber(c *Column, value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		e.add(c.Name, value, "required field is missing")
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		e.add(c.Name, value, "not a valid number")
+		return 0, false
+	}
+	if c.Integer && v != math.Trunc(v) {
+		e.add(c.Name, value, "must be a whole number")
+		return v, false
+	}
+	if (c.Min != nil && v < *c.Min) || (c.Max != nil && v > *c.Max) {
+		e.add(c.Name, value, "must be "+rangeDescription(c.Min, c.Max))
+		return v, false
+	}
+	return v, true
+}
+
+func rangeDescription(min, max *float64) string {
+	switch {
+	case min != nil && max != nil:
+		return fmt.Sprintf("between %g and %g", *min, *max)
+	case min != nil:
+		return fmt.Sprintf("at least %g", *min)
+	default:
+		return fmt.Sprintf("at most %g", *max)
+	}
+}
+
+func (e *ValidationError) requireDate(c *Column, value string) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		e.add(c.Name, value, "required field is missing")
+		return
+	}
+	format := c.dateFormat()
+	date, err := time.Parse(format, trimmed)
+	if err != nil {
+		label := format
+		if format == defaultDateFormat {
+			label = "YYYY-MM-DD"
+		}
+		e.add(c.Name, value, fmt.Sprintf("not a valid %s date", label))
+		return
+	}
+	// Check already verified the bounds parse.
+	if c.MinDate != "" {
+		if min, _ := time.Parse(format, c.MinDate); date.Before(min) {
+			e.add(c.Name, value, "date is out of range")
+			return
+		}
+	}
+	if c.MaxDate != "" {
+		if max, _ := time.Parse(format, c.MaxDate); !date.Before(max) {
+			e.add(c.Name, value, "date is out of range")
+		}
+	}
+}
+
+// requirePeriod accepts a year followed by the term 0, 1 or 2, e.g. 20242 or
+// 2024-2, with the year within the column's Min and Max.
+func (e *ValidationError) requirePeriod(c *Column, value string) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		e.add(c.Name, value, "required field is missing")
+		return
+	}
+	periodo, err := strconv.ParseFloat(normalizePeriodo(trimmed), 64)
+	if err != nil {
+		e.add(c.Name, value, "not a valid number")
+		return
+	}
+	year, term := float64(int(periodo)/10), int(periodo)%10
+	if periodo != math.Trunc(periodo) || (c.Min != nil && year < *c.Min) || (c.Max != nil && year > *c.Max) || term > 2 {
+		e.add(c.Name, value, "must be a year followed by term 0, 1 or 2, e.g. 20242")
+	}
+}
+
+func (e *ValidationError) requireOneOf(field, value string, allowed []string) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		e.add(field, value, "required field is missing")
+		return
+	}
+	for _, a := range allowed {
+		if trimmed == strings.TrimSpace(a) {
+			return
+		}
+	}
+	e.add(field, value, "unknown category")
+}
+
+// Validate reports every value of a record that Features would otherwise
+// silently coerce to zero: missing values, unparsable numbers and dates,
+// values out of range and unknown categories. Excluded columns are not
+// checked. It returns nil or a *ValidationError.
+func (s *FeatureSchema) Validate(value func(column string) string) error {
+	verr := &ValidationError{}
+	numbers := make(map[string]float64)
+	for i := range s.Columns {
+		c := &s.Columns[i]
+		if c.Excluded {
+			continue
+		}
+		v := value(c.Name)
+		switch c.Type {
+		case Numeric:
+			if n, ok := verr.requireNumber(c, v); ok {
+				numbers[c.Name] = n
+			}
+		case Date:
+			verr.requireDate(c, v)
+		case Period:
+			verr.requirePeriod(c, v)
+		case Boolean:
+			allowed := c.Values
+			if len(allowed) == 0 {
+				allowed = []string{c.True}
+			}
+			verr.requireOneOf(c.Name, v, allowed)
+		case Category:
+			verr.requireOneOf(c.Name, v, c.Values)
+		}
+	}
+	for _, c := range s.Columns {
+		if c.MaxColumn == "" || c.Excluded {
+			continue
+		}
+		n, ok := numbers[c.Name]
+		limit, okLimit := numbers[c.MaxColumn]
+		if ok && okLimit && n > limit {
+			verr.add(c.Name, value(c.Name), "cannot exceed "+c.MaxColumn)
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}